@@ -0,0 +1,62 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+        http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/poneding/mdi/pkg/mdi"
+	"github.com/spf13/cobra"
+)
+
+var buildCmd = &cobra.Command{
+	Use:          "build",
+	Short:        "Build a static html site of the markdown files",
+	Long:         `Build a static html site of the index pages and markdown files, with breadcrumb and prev/next nav, without editing the markdown files.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return build()
+	},
+}
+
+var buildOut string
+
+func build() error {
+	idx, err := mdi.NewIndex(indexOpt)
+	if idx == nil {
+		return err
+	}
+	// the site is written to the host, also for a read-only git revision
+	p, planErr := idx.PlanBuildFS(mdi.OSFS{}, genOpt, buildOut)
+	if genDryRun {
+		return errors.Join(err, planErr, p.WriteSummary(os.Stdout))
+	}
+	return errors.Join(err, planErr, p.Apply(genOpt))
+}
+
+func init() {
+	addIndexFlags(buildCmd)
+	addGitRefFlag(buildCmd)
+	buildCmd.Flags().StringVar(&buildOut, "out", "site", "Specify the output directory of the site.")
+	buildCmd.Flags().BoolVar(&genOpt.NoHeaderLink, "no-header-link", false, "Do not generate header link in index pages, default is `false`.")
+	buildCmd.Flags().StringVar(&templateDir, "template-dir", "", "Specify the directory of page.html and index.tmpl templates, built-in templates are used for missing files.")
+	buildCmd.Flags().BoolVar(&genDryRun, "dry-run", false, "Print the files to write instead of writing them, default is `false`.")
+	buildCmd.Flags().BoolVarP(&genOpt.Verbose, "verbose", "v", false, "Show verbose log, default is `false`.")
+
+	rootCmd.AddCommand(buildCmd)
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+        http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/poneding/mdi/pkg/mdi"
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:          "check",
+	Short:        "Check markdown index is up to date",
+	Long:         `Check markdown index and navigation are up to date, exit with non-zero code if any file is stale. Accepts the same flags as gen.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return check()
+	},
+}
+
+func check() error {
+	idx, err := mdi.NewIndex(indexOpt)
+	if err != nil {
+		return err
+	}
+	p, err := idx.Plan(genOpt)
+	if err != nil {
+		return err
+	}
+
+	var stale int
+	for _, c := range p.Changes {
+		if c.Kind == mdi.Unchanged {
+			continue
+		}
+		stale++
+		fmt.Printf("STALE: %s (%s)\n", c.File, c.Kind)
+	}
+	if stale > 0 {
+		return fmt.Errorf("%d file(s) out of date, run `mdi gen` with the same flags to update them", stale)
+	}
+	if genOpt.Verbose {
+		fmt.Printf("OK: %d file(s) up to date\n", len(p.Changes))
+	}
+	return nil
+}
+
+func init() {
+	addGenFlags(checkCmd)
+	addGitRefFlag(checkCmd)
+
+	rootCmd.AddCommand(checkCmd)
+}
@@ -22,11 +22,12 @@ import (
 )
 
 var cleanCmd = &cobra.Command{
-	Use:   "clean",
-	Short: "Clean",
-	Long:  `Clean`,
-	Run: func(cmd *cobra.Command, args []string) {
-		mdi.Clean(cleanWorkDir, cleanIndexFile)
+	Use:          "clean",
+	Short:        "Clean",
+	Long:         `Clean`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return mdi.Clean(cleanWorkDir, cleanIndexFile)
 	},
 }
 
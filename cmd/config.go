@@ -0,0 +1,160 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+        http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/poneding/mdi/pkg/mdi"
+	"github.com/spf13/cobra"
+)
+
+var configFile string
+
+var templateDir string
+
+var gitRef string
+
+// loadConfig fills indexOpt and genOpt from the config file, flags set on
+// the command line take precedence.
+func loadConfig(cmd *cobra.Command, args []string) error {
+	if err := useGitRef(); err != nil {
+		return err
+	}
+	fsys, templateFS := fs.FS(mdi.OSFS{}), fs.FS(mdi.OSFS{})
+	if indexOpt.FS != nil {
+		fsys = indexOpt.FS
+	}
+
+	file := configFile
+	if file == "" {
+		file = path.Join(indexOpt.WorkDir, mdi.ConfigFile)
+	}
+	cfg, err := mdi.LoadConfigFS(fsys, file)
+	if err != nil {
+		return err
+	}
+	if cfg != nil {
+		applyConfig(cmd, cfg)
+		// paths of the config file are relative to it
+		relative := func(flag string, p *string, v string) {
+			if !cmd.Flags().Changed(flag) && v != "" {
+				*p = v
+				if !path.IsAbs(v) {
+					*p = path.Join(path.Dir(file), v)
+				}
+			}
+		}
+		relative("template-dir", &templateDir, cfg.TemplateDir)
+		relative("output", &genOpt.Output, cfg.Output)
+		if !cmd.Flags().Changed("template-dir") && cfg.TemplateDir != "" {
+			// next to the config file, in the git revision too
+			templateFS = fsys
+		}
+	}
+	if templateDir != "" {
+		if genOpt.Templates, err = mdi.LoadTemplatesFS(templateFS, templateDir); err != nil {
+			return err
+		}
+	}
+	if err := mdi.ValidateFormat(genOpt.Format); err != nil {
+		return err
+	}
+	if err := mdi.ValidateTOCDepth(genOpt.TOCMinDepth, genOpt.TOCMaxDepth); err != nil {
+		return err
+	}
+	if output := genOpt.OutputFile(indexOpt.WorkDir); path.Ext(output) == ".md" {
+		// the output file is not an entry of itself
+		indexOpt.Excludes = append(indexOpt.Excludes, path.Base(output))
+	}
+	return mdi.ValidateSort(indexOpt.Sort)
+}
+
+// useGitRef reads the tree from the git revision of --git-ref: the paths of
+// the flags, relative to the current directory, become paths relative to
+// the root of the repository.
+func useGitRef() error {
+	if gitRef == "" {
+		return nil
+	}
+	g, err := mdi.NewGitFS(".", gitRef)
+	if err != nil {
+		return err
+	}
+	for _, p := range []*string{&indexOpt.WorkDir, &indexOpt.RootIndexFile, &configFile, &genOpt.Output} {
+		if *p == "" {
+			continue
+		}
+		abs, err := filepath.Abs(*p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(g.Root(), abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%s is outside of the git repository %s", *p, g.Root())
+		}
+		*p = filepath.ToSlash(rel)
+	}
+	indexOpt.FS = g
+	return nil
+}
+
+func applyConfig(cmd *cobra.Command, cfg *mdi.Config) {
+	changed := cmd.Flags().Changed
+	setString := func(flag string, p *string, v string) {
+		if !changed(flag) && v != "" {
+			*p = v
+		}
+	}
+	setBool := func(flag string, p *bool, v *bool) {
+		if !changed(flag) && v != nil {
+			*p = *v
+		}
+	}
+	setInt := func(flag string, p *int, v int) {
+		if !changed(flag) && v != 0 {
+			*p = v
+		}
+	}
+
+	setString("index-title", &indexOpt.IndexTitle, cfg.Title)
+	setString("index-title", &indexOpt.IndexTitle, cfg.IndexTitle)
+	setString("home-title", &indexOpt.HomeTitle, cfg.HomeTitle)
+	setString("root-index-file", &indexOpt.RootIndexFile, cfg.RootIndexFile)
+	setString("sub-index-file", &indexOpt.SubIndexFile, cfg.SubIndexFile)
+	setString("sort", &indexOpt.Sort, cfg.Sort)
+	setString("format", &genOpt.Format, cfg.Format)
+	setBool("inherit-gitignore", &indexOpt.InheritGitIgnore, cfg.InheritGitIgnore)
+	setBool("override", &genOpt.Override, cfg.Override)
+	setBool("recursive", &genOpt.Recursive, cfg.Recursive)
+	setBool("no-header-link", &genOpt.NoHeaderLink, cfg.NoHeaderLink)
+	setBool("nav", &genOpt.Nav, cfg.Nav)
+	setBool("verbose", &genOpt.Verbose, cfg.Verbose)
+	setBool("toc", &genOpt.TOC, cfg.TOC)
+	setInt("toc-min-depth", &genOpt.TOCMinDepth, cfg.TOCMinDepth)
+	setInt("toc-max-depth", &genOpt.TOCMaxDepth, cfg.TOCMaxDepth)
+	setBool("last-updated", &genOpt.LastUpdated, cfg.LastUpdated)
+	setInt("recent", &genOpt.Recent, cfg.Recent)
+	setBool("tags", &genOpt.Tags, cfg.Tags)
+	setBool("backlinks", &genOpt.Backlinks, cfg.Backlinks)
+	indexOpt.Excludes = append(indexOpt.Excludes, cfg.Exclude...)
+	indexOpt.Order = cfg.Order
+}
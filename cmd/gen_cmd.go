@@ -17,16 +17,22 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+
 	"github.com/poneding/mdi/pkg/mdi"
 	"github.com/spf13/cobra"
 )
 
 var genCmd = &cobra.Command{
-	Use:   "gen",
-	Short: "Generate markdown index",
-	Long:  `Generate markdown index`,
-	Run: func(cmd *cobra.Command, args []string) {
-		run()
+	Use:          "gen",
+	Short:        "Generate markdown index",
+	Long:         `Generate markdown index`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return run()
 	},
 }
 
@@ -34,22 +40,86 @@ var indexOpt = &mdi.IndexOption{}
 
 var genOpt = &mdi.GenerationOption{}
 
-func run() {
-	mdi.NewIndex(indexOpt).Generate(genOpt)
+var genDryRun bool
+
+var genWatch bool
+
+var genOutDir string
+
+func run() error {
+	if gitRef != "" && genWatch {
+		return errors.New("--watch cannot be used with --git-ref")
+	}
+	if gitRef != "" && !genDryRun && genOutDir == "" {
+		return errors.New("--git-ref requires --out-dir or --dry-run, the revision is read-only")
+	}
+	if genWatch {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return mdi.Watch(ctx, indexOpt, genOpt, mdi.DefaultDebounce)
+	}
+
+	idx, err := mdi.NewIndex(indexOpt)
+	if idx == nil {
+		return err
+	}
+	if genDryRun {
+		p, planErr := idx.Plan(genOpt)
+		return errors.Join(err, planErr, p.WriteDiff(os.Stdout))
+	}
+	if genOutDir != "" {
+		p, planErr := idx.Plan(genOpt)
+		return errors.Join(err, planErr, p.ApplyTo(mdi.DirFS(genOutDir), genOpt))
+	}
+	return errors.Join(err, idx.Generate(genOpt))
 }
 
 func init() {
-	genCmd.Flags().StringVarP(&indexOpt.WorkDir, "workdir", "d", ".", "Specify the directory to generate markdown index.")
-	genCmd.Flags().StringVarP(&indexOpt.IndexTitle, "index-title", "t", "", "Specify the title of markdown index, default is title of markdown index file or current directory name.")
-	genCmd.Flags().StringVar(&indexOpt.HomeTitle, "home-title", "", "Specify the title of home link in markdown index, if not specified, use `index-title`.")
-	genCmd.Flags().StringVarP(&indexOpt.RootIndexFile, "root-index-file", "f", "zz_generated_mdi.md", "Specify the markdown root index file, default is `zz_generated_mdi.md`.")
-	genCmd.Flags().StringVar(&indexOpt.SubIndexFile, "sub-index-file", "zz_generated_mdi.md", "Specify the markdown sub index file, default is `zz_generated_mdi.md`.")
-	genCmd.Flags().BoolVar(&indexOpt.InheritGitIgnore, "inherit-gitignore", true, "Use `.gitignore` file as ignore file, default is `true`.")
-	genCmd.Flags().BoolVar(&genOpt.Override, "override", false, "Override markdown existing index file, default is `false`.")
-	genCmd.Flags().BoolVarP(&genOpt.Recursive, "recursive", "r", false, "Recursively generate markdown index in subdirectories, default is `false`.")
-	genCmd.Flags().BoolVar(&genOpt.NoHeaderLink, "no-header-link", false, "Do not generate header link in index file, default is `false`.")
-	genCmd.Flags().BoolVar(&genOpt.Nav, "nav", false, "Generate navigation in markdown file, default is `false`.")
-	genCmd.Flags().BoolVarP(&genOpt.Verbose, "verbose", "v", false, "Show verbose log, default is `false`.")
+	addGenFlags(genCmd)
+	genCmd.Flags().BoolVar(&genDryRun, "dry-run", false, "Print a unified diff of the planned changes instead of writing files, default is `false`.")
+	genCmd.Flags().BoolVar(&genDryRun, "diff", false, "Alias of `--dry-run`.")
+	genCmd.Flags().BoolVarP(&genWatch, "watch", "w", false, "Watch workdir and regenerate markdown index on changes, default is `false`.")
+	genCmd.Flags().StringVar(&genOutDir, "out-dir", "", "Write the created and modified files under this directory, at their path in the tree, instead of in place.")
+	addGitRefFlag(genCmd)
 
 	rootCmd.AddCommand(genCmd)
 }
+
+// addGenFlags registers the flags shared by the commands running the generation pipeline.
+func addGenFlags(cmd *cobra.Command) {
+	addIndexFlags(cmd)
+	cmd.Flags().BoolVar(&genOpt.Override, "override", false, "Override markdown existing index file, default is `false`.")
+	cmd.Flags().BoolVarP(&genOpt.Recursive, "recursive", "r", false, "Recursively generate markdown index in subdirectories, default is `false`.")
+	cmd.Flags().BoolVar(&genOpt.NoHeaderLink, "no-header-link", false, "Do not generate header link in index file, default is `false`.")
+	cmd.Flags().StringVar(&templateDir, "template-dir", "", "Specify the directory of index.tmpl, breadcrumb.tmpl, footer.tmpl, tags.tmpl and backlinks.tmpl templates, built-in templates are used for missing files.")
+	cmd.Flags().StringVar(&genOpt.Format, "format", mdi.FormatMarkdown, "Specify the output format, markdown writes index files, summary, mkdocs and docusaurus write a single mdBook SUMMARY.md, MkDocs nav or Docusaurus sidebars file.")
+	cmd.Flags().StringVarP(&genOpt.Output, "output", "o", "", "Specify the output file of the summary, mkdocs and docusaurus formats, default is SUMMARY.md, mkdocs.yml or sidebars.json in workdir.")
+	cmd.Flags().BoolVar(&genOpt.Nav, "nav", false, "Generate navigation in markdown file, default is `false`.")
+	cmd.Flags().BoolVar(&genOpt.TOC, "toc", false, "Generate table of contents between <!-- mdi:toc --> and <!-- /mdi:toc --> markers in markdown file, default is `false`.")
+	cmd.Flags().IntVar(&genOpt.TOCMinDepth, "toc-min-depth", mdi.DefaultTOCMinDepth, "Specify the minimum heading depth of table of contents, default is `2`.")
+	cmd.Flags().IntVar(&genOpt.TOCMaxDepth, "toc-max-depth", mdi.DefaultTOCMaxDepth, "Specify the maximum heading depth of table of contents, default is `3`.")
+	cmd.Flags().BoolVar(&genOpt.LastUpdated, "last-updated", false, "Annotate the entries of index file with the date and author of their last git commit, default is `false`.")
+	cmd.Flags().IntVar(&genOpt.Recent, "recent", 0, "Specify the number of entries last changed in git listed in a Recently updated section of root index file, default is `0`, no section.")
+	cmd.Flags().BoolVar(&genOpt.Tags, "tags", false, "Generate tags.md listing the tags of markdown files, from front matter tags and inline #tag, and a page per tag in the tags directory of workdir, default is `false`.")
+	cmd.Flags().BoolVar(&genOpt.Backlinks, "backlinks", false, "Generate a Linked from section listing the markdown files linking to each markdown file, between <!-- mdi:backlinks:start --> and <!-- mdi:backlinks:end --> markers, default is `false`.")
+	cmd.Flags().BoolVarP(&genOpt.Verbose, "verbose", "v", false, "Show verbose log, default is `false`.")
+}
+
+// addGitRefFlag registers --git-ref on the commands able to read the tree
+// from a git revision.
+func addGitRefFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&gitRef, "git-ref", "", "Read the markdown files, config and ignore files at the git revision, like origin/main, of the repository of the current directory instead of the working tree.")
+}
+
+// addIndexFlags registers the flags of the commands building the index tree.
+func addIndexFlags(cmd *cobra.Command) {
+	cmd.PreRunE = loadConfig
+	cmd.Flags().StringVar(&configFile, "config", "", "Specify the config file, default is `.mdi.yaml` in workdir.")
+	cmd.Flags().StringVarP(&indexOpt.WorkDir, "workdir", "d", ".", "Specify the directory to generate markdown index.")
+	cmd.Flags().StringVarP(&indexOpt.IndexTitle, "index-title", "t", "", "Specify the title of markdown index, default is title of markdown index file or current directory name.")
+	cmd.Flags().StringVar(&indexOpt.HomeTitle, "home-title", "", "Specify the title of home link in markdown index, if not specified, use `index-title`.")
+	cmd.Flags().StringVarP(&indexOpt.RootIndexFile, "root-index-file", "f", "zz_generated_mdi.md", "Specify the markdown root index file, default is `zz_generated_mdi.md`.")
+	cmd.Flags().StringVar(&indexOpt.SubIndexFile, "sub-index-file", "zz_generated_mdi.md", "Specify the markdown sub index file, default is `zz_generated_mdi.md`.")
+	cmd.Flags().StringVar(&indexOpt.Sort, "sort", mdi.DefaultSort, "Specify the comma separated sort keys of entries, available keys: order, weight, name, natural, title, mtime, git, prefix a key with - to reverse it.")
+	cmd.Flags().BoolVar(&indexOpt.InheritGitIgnore, "inherit-gitignore", true, "Use `.gitignore` file as ignore file, default is `true`.")
+}
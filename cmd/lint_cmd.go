@@ -0,0 +1,69 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+        http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/poneding/mdi/pkg/mdi"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Lint markdown files",
+	Long:  `Lint markdown files`,
+}
+
+var lintLinksCmd = &cobra.Command{
+	Use:          "links",
+	Short:        "Check relative links of markdown files",
+	Long:         `Check relative links and #anchor fragments of the index files and markdown files, exit with non-zero code if any link is broken or points to an excluded file.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return lintLinks()
+	},
+}
+
+func lintLinks() error {
+	idx, err := mdi.NewIndex(indexOpt)
+	if idx == nil {
+		return err
+	}
+	issues, lintErr := idx.LintLinks()
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	var brokenErr error
+	if len(issues) > 0 {
+		brokenErr = fmt.Errorf("%d broken link(s)", len(issues))
+	}
+	if err = errors.Join(err, lintErr, brokenErr); err == nil && genOpt.Verbose {
+		fmt.Printf("OK: no broken links\n")
+	}
+	return err
+}
+
+func init() {
+	addIndexFlags(lintLinksCmd)
+	addGitRefFlag(lintLinksCmd)
+	lintLinksCmd.Flags().BoolVarP(&genOpt.Verbose, "verbose", "v", false, "Show verbose log, default is `false`.")
+
+	lintCmd.AddCommand(lintLinksCmd)
+	rootCmd.AddCommand(lintCmd)
+}
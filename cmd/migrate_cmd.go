@@ -0,0 +1,55 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+        http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"os"
+
+	"github.com/poneding/mdi/pkg/mdi"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:          "migrate",
+	Short:        "Wrap the nav of markdown files decorated by older versions in markers",
+	Long:         `Wrap the breadcrumb and prev/next nav written by older versions of mdi in markers, so that gen and clean find them without guessing. Other content is left untouched.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return migrate()
+	},
+}
+
+func migrate() error {
+	idx, err := mdi.NewIndex(indexOpt)
+	if idx == nil {
+		return err
+	}
+	if genDryRun {
+		p, planErr := idx.PlanMigrate()
+		return errors.Join(err, planErr, p.WriteDiff(os.Stdout))
+	}
+	return errors.Join(err, idx.Migrate(genOpt))
+}
+
+func init() {
+	addIndexFlags(migrateCmd)
+	migrateCmd.Flags().BoolVar(&genDryRun, "dry-run", false, "Print a unified diff of the planned changes instead of writing files, default is `false`.")
+	migrateCmd.Flags().BoolVarP(&genOpt.Verbose, "verbose", "v", false, "Show verbose log, default is `false`.")
+
+	rootCmd.AddCommand(migrateCmd)
+}
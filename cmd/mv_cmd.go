@@ -0,0 +1,39 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+        http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/poneding/mdi/pkg/mdi"
+	"github.com/spf13/cobra"
+)
+
+var mvCmd = &cobra.Command{
+	Use:          "mv <old> <new>",
+	Short:        "Move a markdown file or directory and rewrite links to it",
+	Long:         `Move a markdown file or directory, into <new> if it is an existing directory, rewrite the relative links to the moved files and of the moved files, then regenerate markdown index. Accepts the same flags as gen.`,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return mdi.Move(indexOpt, genOpt, args[0], args[1])
+	},
+}
+
+func init() {
+	addGenFlags(mvCmd)
+
+	rootCmd.AddCommand(mvCmd)
+}
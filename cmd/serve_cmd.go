@@ -0,0 +1,58 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+        http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/poneding/mdi/pkg/mdi"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:          "serve",
+	Short:        "Serve a live preview of the markdown files",
+	Long:         `Serve the html site of mdi build on localhost, rebuilt on file changes with open pages reloaded. Markdown files are not edited.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return serve()
+	},
+}
+
+var serveAddr string
+
+func serve() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return mdi.Serve(ctx, serveAddr, indexOpt, genOpt, func(addr string) {
+		fmt.Printf("Serving %s on http://%s, press Ctrl+C to stop\n", indexOpt.WorkDir, addr)
+	})
+}
+
+func init() {
+	addIndexFlags(serveCmd)
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "localhost:8080", "Specify the address to listen on.")
+	serveCmd.Flags().BoolVar(&genOpt.NoHeaderLink, "no-header-link", false, "Do not generate header link in index pages, default is `false`.")
+	serveCmd.Flags().StringVar(&templateDir, "template-dir", "", "Specify the directory of page.html and index.tmpl templates, built-in templates are used for missing files.")
+	serveCmd.Flags().BoolVar(&genOpt.Nav, "nav", false, "Render the breadcrumb and prev/next navigation of --nav in pages, default is `false`.")
+	serveCmd.Flags().BoolVarP(&genOpt.Verbose, "verbose", "v", false, "Show verbose log, default is `false`.")
+
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+        http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/poneding/mdi/pkg/mdi"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var treeCmd = &cobra.Command{
+	Use:          "tree",
+	Short:        "Print the index tree as JSON or YAML",
+	Long:         `Print the index tree as JSON or YAML: indexes, entries, titles, files relative to workdir, breadcrumbs and prev/next entries.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return tree()
+	},
+}
+
+var treeOutput string
+
+var treeVerbose bool
+
+func tree() error {
+	if treeOutput != "json" && treeOutput != "yaml" {
+		return fmt.Errorf("invalid output: %s, available outputs: json, yaml", treeOutput)
+	}
+
+	idx, err := mdi.NewIndex(indexOpt)
+	if idx == nil {
+		return err
+	}
+	dump := idx.Dump(treeVerbose)
+
+	switch treeOutput {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(dump); err != nil {
+			return err
+		}
+	case "yaml":
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(dump); err != nil {
+			return err
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func init() {
+	addIndexFlags(treeCmd)
+	addGitRefFlag(treeCmd)
+	treeCmd.Flags().StringVarP(&treeOutput, "output", "o", "json", "Specify the output format, json or yaml.")
+	treeCmd.Flags().BoolVarP(&treeVerbose, "verbose", "v", false, "Include the skipped paths of every index and the ignore rule excluding them, default is `false`.")
+
+	rootCmd.AddCommand(treeCmd)
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"errors"
+	"io/fs"
+	"net/url"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// readBacklinks returns the entries of the tree linking to each entry, in
+// the order of the tree. The links of the generated regions are ignored.
+func (idx *Index) readBacklinks() (map[string][]*Entry, error) {
+	var entries []*Entry
+	idx.Walk(func(i *Index) error {
+		entries = append(entries, i.entries...)
+		return nil
+	})
+	files := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		files[e.file] = true
+	}
+
+	md := newMarkdown()
+	backlinks := make(map[string][]*Entry)
+	var errs []error
+	for _, e := range entries {
+		b, err := fs.ReadFile(idx.fsys, e.file)
+		if err != nil {
+			errs = append(errs, newError("read file", e.file, err))
+			continue
+		}
+		content, _ := normalizeEOL(string(b))
+		_, body := splitFrontMatter(content)
+		body = removeBacklinks(md, removeNav(md, removeTOC(md, body)))
+		for _, target := range linkTargets(md, e.file, []byte(body)) {
+			if files[target] && target != e.file && !slices.Contains(backlinks[target], e) {
+				backlinks[target] = append(backlinks[target], e)
+			}
+		}
+	}
+	return backlinks, errors.Join(errs...)
+}
+
+// linkTargets returns the files linked by the relative links of src, the
+// body of file.
+func linkTargets(md goldmark.Markdown, file string, src []byte) []string {
+	var targets []string
+	ast.Walk(md.Parser().Parse(text.NewReader(src)), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		link, ok := n.(*ast.Link)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		u, err := url.Parse(string(link.Destination))
+		if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || path.IsAbs(u.Path) {
+			return ast.WalkContinue, nil
+		}
+		targets = append(targets, path.Join(path.Dir(file), u.Path))
+		return ast.WalkContinue, nil
+	})
+	return targets
+}
+
+// renderBacklinks renders the backlinks section of an entry, empty if no
+// entry links to it.
+func (e *Entry) renderBacklinks(t *Templates, referrers []*Entry) (string, error) {
+	data := &BacklinksData{}
+	for _, r := range referrers {
+		relPath, _ := filepath.Rel(path.Dir(e.file), r.file)
+		data.Links = append(data.Links, &Link{Title: r.navTitle(), Link: getLink(filepath.ToSlash(relPath))})
+	}
+	return execTemplate(t.backlinks, data)
+}
+
+// updateBacklinks replaces the backlinks section between the markers of
+// body, or inserts it before the footer nav or at the end of body. The
+// section is removed if content is empty.
+func updateBacklinks(md goldmark.Markdown, body, content string) string {
+	r := findRegion(md, []byte(body), BacklinksStart, BacklinksEnd)
+	switch {
+	case r.found() && content != "":
+		return r.replace(body, content)
+	case r.found():
+		return r.remove(body)
+	case content == "":
+		return body
+	}
+
+	pos := len(body)
+	if footer := findRegion(md, []byte(body), FooterStart, FooterEnd); footer.found() {
+		pos = footer.start
+	}
+	// the section is a block of its own
+	before, after := body[:pos], body[pos:]
+	for before != "" && !strings.HasSuffix(before, "\n\n") {
+		before += "\n"
+	}
+	if after != "" {
+		after = "\n" + after
+	}
+	return before + r.wrap(content) + after
+}
+
+// removeBacklinks removes the backlinks section and its markers from body.
+func removeBacklinks(md goldmark.Markdown, body string) string {
+	if r := findRegion(md, []byte(body), BacklinksStart, BacklinksEnd); r.found() {
+		return r.remove(body)
+	}
+	return body
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"io/fs"
+	"slices"
+	"testing"
+)
+
+func TestLinkTargets(t *testing.T) {
+	testdata := []struct {
+		src      string
+		expected []string
+	}{
+		{"[a](a.md) and [b](../rust/b.md#intro)\n", []string{"go/a.md", "rust/b.md"}},
+		{"[space](my%20notes.md) and [dot](./a.md)\n", []string{"go/my notes.md", "go/a.md"}},
+		{"[web](https://go.dev) [abs](/a.md) [anchor](#usage) ![img](a.png)\n", nil},
+		{"`[code](a.md)`\n", nil},
+	}
+
+	md := newMarkdown()
+	for _, d := range testdata {
+		if targets := linkTargets(md, "go/hello.md", []byte(d.src)); !slices.Equal(targets, d.expected) {
+			t.Errorf("linkTargets(%q) = %q, expected %q", d.src, targets, d.expected)
+		}
+	}
+}
+
+func TestUpdateBacklinks(t *testing.T) {
+	section := "## Linked from\n\n- [A](a.md)\n"
+	backlinks := BacklinksStart + "\n" + section + BacklinksEnd + "\n"
+	footer := FooterStart + "\n---\n[» A](a.md)\n" + FooterEnd + "\n"
+	testdata := []struct {
+		body     string
+		content  string
+		expected string
+	}{
+		// appended to the body
+		{"# Hello\n", section, "# Hello\n\n" + backlinks},
+		{"# Hello", section, "# Hello\n\n" + backlinks},
+		// inserted before the footer nav
+		{"# Hello\n\n" + footer, section, "# Hello\n\n" + backlinks + "\n" + footer},
+		// updated between the markers
+		{"# Hello\n\n" + BacklinksStart + "\n- [Old](old.md)\n" + BacklinksEnd + "\n\n" + footer, section, "# Hello\n\n" + backlinks + "\n" + footer},
+		// removed without backlinks
+		{"# Hello\n\n" + backlinks + "\n" + footer, "", "# Hello\n\n" + footer},
+		{"# Hello\n\n" + backlinks, "", "# Hello\n"},
+		{"# Hello\n", "", "# Hello\n"},
+	}
+
+	md := newMarkdown()
+	for _, d := range testdata {
+		if actual := updateBacklinks(md, d.body, d.content); actual != d.expected {
+			t.Errorf("updateBacklinks(%q, %q) = %q, expected %q", d.body, d.content, actual, d.expected)
+		}
+	}
+}
+
+func TestGenerateBacklinks(t *testing.T) {
+	m := NewMemFS(map[string]string{
+		"README.md":     "# Notes\n",
+		"go/hello.md":   "# Hello\n\nSee [intro](../rust/intro.md#setup) and [hello](hello.md).\n",
+		"go/world.md":   "---\nnav_title: W\n---\n# World\n\nBack to [hello](hello.md).\n",
+		"rust/intro.md": "# Intro\n\n## Setup\n",
+	})
+	idxOpt := &IndexOption{FS: m, WorkDir: ".", IndexTitle: "Notes", RootIndexFile: "README.md", SubIndexFile: defaultIndexFile}
+	idx, err := NewIndex(idxOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genOpt := &GenerationOption{Recursive: true, Nav: true, Backlinks: true}
+	if err := idx.Generate(genOpt); err != nil {
+		t.Fatal(err)
+	}
+
+	testdata := []struct {
+		file     string
+		expected string
+	}{
+		// the prev/next links of the footer are not backlinks
+		{"go/hello.md", NavStart + "\n[Notes](../README.md) / [go](zz_generated_mdi.md) / Hello\n" + NavEnd + "\n\n# Hello\n\nSee [intro](../rust/intro.md#setup) and [hello](hello.md).\n\n" +
+			BacklinksStart + "\n## Linked from\n\n- [W](world.md)\n" + BacklinksEnd + "\n\n" +
+			FooterStart + "\n---\n[» W](world.md)\n" + FooterEnd + "\n"},
+		{"go/world.md", "---\nnav_title: W\n---\n" + NavStart + "\n[Notes](../README.md) / [go](zz_generated_mdi.md) / W\n" + NavEnd + "\n\n# World\n\nBack to [hello](hello.md).\n\n" +
+			FooterStart + "\n---\n[« Hello](hello.md)\n" + FooterEnd + "\n"},
+		{"rust/intro.md", NavStart + "\n[Notes](../README.md) / [rust](zz_generated_mdi.md) / Intro\n" + NavEnd + "\n\n# Intro\n\n## Setup\n\n" +
+			BacklinksStart + "\n## Linked from\n\n- [Hello](../go/hello.md)\n" + BacklinksEnd + "\n"},
+	}
+	for _, d := range testdata {
+		if b, err := fs.ReadFile(m, d.file); err != nil || string(b) != d.expected {
+			t.Errorf("ReadFile(%q) = %q, %v, expected %q", d.file, b, err, d.expected)
+		}
+	}
+
+	p, err := idx.Plan(genOpt)
+	if err != nil || p.Count(Created)+p.Count(Modified) != 0 {
+		t.Errorf("Plan() = %d created, %d modified files, %v, expected none", p.Count(Created), p.Count(Modified), err)
+	}
+
+	if err := CleanFS(m, ".", defaultIndexFile); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := fs.ReadFile(m, "rust/intro.md"); string(b) != "# Intro\n\n## Setup\n" {
+		t.Errorf("ReadFile(%q) = %q after CleanFS, expected %q", "rust/intro.md", b, "# Intro\n\n## Setup\n")
+	}
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"bytes"
+	"errors"
+	htmltemplate "html/template"
+	"io/fs"
+	"net/url"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// SiteIndexFile is the html page of every index in the output of Build.
+const SiteIndexFile = "index.html"
+
+// PageData is the data of the page template of Build, for both index
+// pages and entries. Links are relative to the page.
+type PageData struct {
+	// SiteTitle is the title of the root index.
+	SiteTitle string
+	Title     string
+	NavTitle  string
+	// Content is the rendered markdown of the page.
+	Content htmltemplate.HTML
+	// Crumbs link to the parent index pages, from the root index down.
+	Crumbs []*Link
+	// Prev and Next are the neighbor entries, nil at the ends and for index pages.
+	Prev *Link
+	Next *Link
+	// Root is the relative path of the site root, empty or ending with a slash.
+	Root string
+}
+
+// site is the state of one rendering of the html site.
+type site struct {
+	root *Index
+	// nav renders the breadcrumb and prev/next nav of the pages.
+	nav bool
+	// pages maps the source files to their page, relative to the site root.
+	pages map[string]string
+	// assets are the files linked by pages, copied to the same path.
+	assets map[string]bool
+	md     goldmark.Markdown
+	files  []*siteFile
+}
+
+// siteFile is a page or an asset of the site.
+type siteFile struct {
+	path    string
+	content []byte
+}
+
+// Build renders the index pages and entries of the whole tree to html
+// pages in out, with the same layout as the work dir. Index pages are
+// SiteIndexFile of their directory, links to markdown pages are rewritten
+// to the html pages, and the files they link to are copied. The
+// breadcrumb and prev/next nav are rendered by the page template.
+func (idx *Index) Build(genOpt *GenerationOption, out string) error {
+	p, err := idx.PlanBuild(genOpt, out)
+	return errors.Join(err, p.Apply(genOpt))
+}
+
+// PlanBuild collects the files that Build would write.
+func (idx *Index) PlanBuild(genOpt *GenerationOption, out string) (*Plan, error) {
+	return idx.PlanBuildFS(idx.fsys, genOpt, out)
+}
+
+// PlanBuildFS collects the files that Build would write to out in fsys,
+// like the host for a tree read from a git revision.
+func (idx *Index) PlanBuildFS(fsys fs.FS, genOpt *GenerationOption, out string) (*Plan, error) {
+	files, err := idx.renderSite(genOpt, true)
+	p := &Plan{fsys: fsys}
+	errs := []error{err}
+	for _, f := range files {
+		errs = append(errs, p.add(path.Join(out, f.path), f.content))
+	}
+	return p, errors.Join(errs...)
+}
+
+// newMarkdown returns the markdown parser and renderer of mdi: GitHub
+// flavored, raw html kept.
+func newMarkdown() goldmark.Markdown {
+	return goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+}
+
+// renderSite renders the pages and collects the assets of the site, pages
+// failing to be rendered are left out and reported in the returned error.
+func (idx *Index) renderSite(genOpt *GenerationOption, nav bool) ([]*siteFile, error) {
+	s := &site{
+		root:   idx,
+		nav:    nav,
+		pages:  make(map[string]string),
+		assets: make(map[string]bool),
+		md:     newMarkdown(),
+	}
+	idx.Walk(func(i *Index) error {
+		dir := idx.relPath(i.workDir)
+		s.pages[i.file] = path.Join(dir, SiteIndexFile)
+		for _, e := range i.entries {
+			rel := idx.relPath(e.file)
+			page := strings.TrimSuffix(rel, path.Ext(rel)) + ".html"
+			if path.Base(page) == SiteIndexFile {
+				page = rel + ".html"
+			}
+			s.pages[e.file] = page
+		}
+		return nil
+	})
+
+	t, err := genOpt.templates()
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	idx.Walk(func(i *Index) error {
+		errs = append(errs, s.renderIndex(t, i, genOpt))
+		for _, e := range i.entries {
+			errs = append(errs, s.renderEntry(t, e))
+		}
+		return nil
+	})
+
+	assets := make([]string, 0, len(s.assets))
+	for file := range s.assets {
+		assets = append(assets, file)
+	}
+	slices.Sort(assets)
+	for _, file := range assets {
+		b, err := fs.ReadFile(idx.fsys, file)
+		if err != nil {
+			errs = append(errs, newError("read file", file, err))
+			continue
+		}
+		s.files = append(s.files, &siteFile{path: idx.relPath(file), content: b})
+	}
+	return s.files, errors.Join(errs...)
+}
+
+func (s *site) renderIndex(t *Templates, idx *Index, genOpt *GenerationOption) error {
+	src, err := execTemplate(t.index, idx.indexData(genOpt))
+	if err != nil {
+		return err
+	}
+	page := s.pages[idx.file]
+	data := &PageData{
+		Title:    idx.title,
+		NavTitle: idx.navTitle(),
+		Crumbs:   s.crumbs(idx.chains[:len(idx.chains)-1], page),
+	}
+	return s.renderPage(t, []byte(src), idx.workDir, page, data)
+}
+
+func (s *site) renderEntry(t *Templates, e *Entry) error {
+	b, err := fs.ReadFile(s.root.fsys, e.file)
+	if err != nil {
+		return newError("read file", e.file, err)
+	}
+	content, _ := normalizeEOL(string(b))
+	_, body := splitNavFrontMatter(content)
+	// the nav written by gen is rendered as page chrome instead, its TOC
+	// and backlinks are left out too
+	body = removeNav(s.md, removeTOC(s.md, removeBacklinks(s.md, body)))
+	page := s.pages[e.file]
+	data := &PageData{
+		Title:    e.title,
+		NavTitle: e.navTitle(),
+		Crumbs:   s.crumbs(e.index.chains, page),
+	}
+	if e.prev != nil {
+		data.Prev = &Link{Title: e.prev.navTitle(), Link: s.link(page, s.pages[e.prev.file])}
+	}
+	if e.next != nil {
+		data.Next = &Link{Title: e.next.navTitle(), Link: s.link(page, s.pages[e.next.file])}
+	}
+	return s.renderPage(t, []byte(body), path.Dir(e.file), page, data)
+}
+
+// renderPage renders the markdown src, with links relative to srcDir, to page.
+func (s *site) renderPage(t *Templates, src []byte, srcDir, page string, data *PageData) error {
+	doc := s.md.Parser().Parse(text.NewReader(src))
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link:
+			n.Destination = []byte(s.rewrite(string(n.Destination), srcDir, page))
+		case *ast.Image:
+			n.Destination = []byte(s.rewrite(string(n.Destination), srcDir, page))
+		}
+		return ast.WalkContinue, nil
+	})
+	var content bytes.Buffer
+	if err := s.md.Renderer().Render(&content, src, doc); err != nil {
+		return newError("render", page, err)
+	}
+
+	if !s.nav {
+		data.Crumbs, data.Prev, data.Next = nil, nil, nil
+	}
+	data.SiteTitle = s.root.title
+	data.Content = htmltemplate.HTML(content.String())
+	data.Root = strings.Repeat("../", strings.Count(page, "/"))
+	var buf bytes.Buffer
+	if err := t.page.Execute(&buf, data); err != nil {
+		return newError("execute template", PageTemplate, err)
+	}
+	s.files = append(s.files, &siteFile{path: page, content: buf.Bytes()})
+	return nil
+}
+
+// rewrite rewrites a link to a markdown page to its html page, and records
+// the linked files of the work dir as assets.
+func (s *site) rewrite(dest, srcDir, page string) string {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || path.IsAbs(u.Path) {
+		return dest
+	}
+	target := path.Join(srcDir, u.Path)
+	if to, ok := s.pages[target]; ok {
+		u.Path = s.link(page, to)
+		return u.String()
+	}
+	if rel := s.root.relPath(target); rel != ".." && !strings.HasPrefix(rel, "../") {
+		if fi, err := fs.Stat(s.root.fsys, target); err == nil && fi.Mode().IsRegular() {
+			s.assets[target] = true
+		}
+	}
+	return dest
+}
+
+// crumbs links to the pages of indexes, relative to page.
+func (s *site) crumbs(indexes []*Index, page string) []*Link {
+	var result []*Link
+	for i, idx := range indexes {
+		title := idx.homeTitle
+		if i > 0 {
+			title = idx.navTitle()
+		}
+		result = append(result, &Link{Title: title, Link: s.link(page, s.pages[idx.file])})
+	}
+	return result
+}
+
+// link returns the path of the page to relative to the page from.
+func (s *site) link(from, to string) string {
+	rel, err := filepath.Rel(path.Dir(from), to)
+	if err != nil {
+		return to
+	}
+	return filepath.ToSlash(rel)
+}
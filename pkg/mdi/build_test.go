@@ -0,0 +1,183 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestBuild(t *testing.T) {
+	idx := newTestIndex(t, map[string]string{
+		"root.md":            "# Root\n\nSee [Hello](go/hello.md#usage) and [the docs](https://example.com/a.md).\n",
+		"go/hello.md":        "---\nnav_title: Hi\n---\n# Hello\n\n![logo](img/logo.png) [Go](zz_generated_mdi.md) [missing](gone.md)\n",
+		"go/vars.md":         "# Vars\n",
+		"go/img/logo.png":    "PNG",
+		"my notes/a note.md": "# A Note\n",
+	})
+	out := t.TempDir()
+	if err := idx.Build(&GenerationOption{}, out); err != nil {
+		t.Fatal(err)
+	}
+
+	testdata := []struct {
+		page     string
+		expected []string
+	}{
+		{"index.html", []string{
+			`<title>Notes</title>`,
+			`<a href="go/index.html">go</a>`,
+			`<a href="my%20notes/a%20note.html">A Note</a>`,
+			`<a href="root.html">Root</a>`,
+		}},
+		{"root.html", []string{
+			`<title>Root - Notes</title>`,
+			`<nav class="breadcrumb"><a href="index.html">Notes</a> / Root</nav>`,
+			`<a href="go/hello.html#usage">Hello</a>`,
+			`<a href="https://example.com/a.md">the docs</a>`,
+		}},
+		{"go/index.html", []string{
+			`<nav class="breadcrumb"><a href="../index.html">Notes</a> / go</nav>`,
+			`<a href="hello.html">Hello</a>`,
+		}},
+		{"go/hello.html", []string{
+			`<nav class="breadcrumb"><a href="../index.html">Notes</a> / <a href="index.html">go</a> / Hi</nav>`,
+			`<img src="img/logo.png" alt="logo">`,
+			`<a href="index.html">Go</a>`,
+			`<a href="gone.md">missing</a>`,
+			`<a class="next" href="vars.html">Vars »</a>`,
+		}},
+		{"go/vars.html", []string{`<a class="prev" href="hello.html">« Hi</a>`}},
+		{"go/img/logo.png", []string{"PNG"}},
+	}
+	for _, d := range testdata {
+		b, err := os.ReadFile(path.Join(out, d.page))
+		if err != nil {
+			t.Errorf("Build() did not write %s: %v", d.page, err)
+			continue
+		}
+		for _, e := range d.expected {
+			if !strings.Contains(string(b), e) {
+				t.Errorf("Build() page %s = %s, expected it to contain %s", d.page, b, e)
+			}
+		}
+	}
+	if b, _ := os.ReadFile(path.Join(idx.workDir, "go/hello.md")); strings.Contains(string(b), "[Notes]") {
+		t.Errorf("Build() edited the source file: %s", b)
+	}
+}
+
+func TestBuildGitFS(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rev := commitTree(t, repo, dir, map[string]string{
+		"go/hello.md":     "# Hello\n",
+		"site/index.html": "committed\n",
+	}, time.Now())
+	g, err := NewGitFS(dir, rev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, err := NewIndex(&IndexOption{FS: g, WorkDir: ".", RootIndexFile: "README.md", SubIndexFile: defaultIndexFile, Excludes: []string{"site"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the pages are planned against the output dir, not the revision
+	out := path.Join(t.TempDir(), "site")
+	p, err := idx.PlanBuildFS(OSFS{}, &GenerationOption{}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Count(Unchanged) != 0 {
+		t.Errorf("PlanBuildFS() = %d unchanged files, expected none", p.Count(Unchanged))
+	}
+	if err := p.Apply(&GenerationOption{}); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(path.Join(out, "go/hello.html")); err != nil || !strings.Contains(string(b), "<h1") {
+		t.Errorf("Apply() wrote %q, %v, expected the page of %s", b, err, "go/hello.md")
+	}
+}
+
+func TestBuildDecorated(t *testing.T) {
+	idx := newTestIndex(t, map[string]string{
+		"go/hello.md": "# Hello\n\n## Usage\n\nSee [Vars](vars.md).\n",
+		"go/vars.md":  "# Vars\n",
+	})
+	if err := idx.Generate(&GenerationOption{Recursive: true, Nav: true, TOC: true, Backlinks: true}); err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	if err := idx.Build(&GenerationOption{Nav: true}, out); err != nil {
+		t.Fatal(err)
+	}
+
+	testdata := []struct {
+		page     string
+		expected string
+		count    int
+	}{
+		{"go/hello.html", `<a href="index.html">go</a>`, 1},
+		{"go/hello.html", `<a class="next" href="vars.html">Vars »</a>`, 1},
+		{"go/hello.html", `» Vars`, 0},
+		{"go/hello.html", `href="#usage"`, 0},
+		{"go/hello.html", `<a href="vars.html">Vars</a>`, 1},
+		{"go/vars.html", `Linked from`, 0},
+		{"go/vars.html", `<a href="index.html">go</a>`, 1},
+		{"go/vars.html", `mdi:`, 0},
+	}
+	for _, d := range testdata {
+		b, err := os.ReadFile(path.Join(out, d.page))
+		if err != nil {
+			t.Errorf("Build() did not write %s: %v", d.page, err)
+			continue
+		}
+		if n := strings.Count(string(b), d.expected); n != d.count {
+			t.Errorf("Build() page %s = %s, expected %d of %s, got %d", d.page, b, d.count, d.expected, n)
+		}
+	}
+}
+
+func TestBuildTemplate(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		PageTemplate: `{{range .Crumbs}}{{.Title}} > {{end}}{{.NavTitle}}|{{.Root}}|{{.Content}}`,
+	})
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx := newTestIndex(t, map[string]string{"go/hello.md": "# Hello\n"})
+	out := t.TempDir()
+	if err := idx.Build(&GenerationOption{Templates: templates}, out); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(path.Join(out, "go/hello.html"))
+	if expected := "Notes > go > Hello|../|<h1 id=\"hello\">Hello</h1>\n"; string(b) != expected {
+		t.Errorf("Build() with a custom page template = %q, expected %q", b, expected)
+	}
+}
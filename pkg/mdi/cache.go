@@ -0,0 +1,261 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"path"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// defaultWorkers bounds the goroutines scanning the tree and the concurrent
+// file reads of a run.
+var defaultWorkers = 4 * runtime.GOMAXPROCS(0)
+
+// cache holds the file lookups of a run of NewIndex, it is safe for
+// concurrent use. The watcher keeps its cache between runs and
+// invalidates the changed files.
+type cache struct {
+	fsys fs.FS
+	// sem bounds the concurrent file reads.
+	sem chan struct{}
+	// workers bounds the goroutines scanning the tree.
+	workers chan struct{}
+
+	mu      sync.Mutex
+	files   map[string]*fileInfo
+	hasMd   map[string]bool
+	rules   map[string][]excludeRule
+	configs map[string]dirConfig
+	ignores map[string][]string
+
+	// gitMu serializes the walks of the git history, which are shared by
+	// every directory of a repository.
+	gitMu     sync.Mutex
+	histories map[string]gitHistory
+}
+
+// dirConfig is the config file of a directory, empty if missing or
+// invalid, and the error reading it.
+type dirConfig struct {
+	cfg *Config
+	err error
+}
+
+// fileInfo is what NewIndex reads of a markdown file.
+type fileInfo struct {
+	title string
+	meta  *frontMatter
+	// err is the error reading the file, a missing file is not an error.
+	err error
+}
+
+func newCache(fsys fs.FS, workers int) *cache {
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	return &cache{
+		fsys:      fsys,
+		sem:       make(chan struct{}, workers),
+		workers:   make(chan struct{}, workers),
+		files:     make(map[string]*fileInfo),
+		hasMd:     make(map[string]bool),
+		rules:     make(map[string][]excludeRule),
+		configs:   make(map[string]dirConfig),
+		ignores:   make(map[string][]string),
+		histories: make(map[string]gitHistory),
+	}
+}
+
+// reset drops every cached lookup.
+func (c *cache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.files)
+	clear(c.hasMd)
+	clear(c.rules)
+	clear(c.configs)
+	clear(c.ignores)
+
+	c.gitMu.Lock()
+	defer c.gitMu.Unlock()
+	clear(c.histories)
+}
+
+// invalidate drops the cached title and front matter of file and the
+// cached md file lookups of file and its parent directories.
+func (c *cache) invalidate(file string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.files, file)
+	for p := file; ; p = path.Dir(p) {
+		delete(c.hasMd, p)
+		if p == path.Dir(p) {
+			break
+		}
+	}
+}
+
+// readFile reads file, waiting for a free worker.
+func (c *cache) readFile(file string) ([]byte, error) {
+	c.sem <- struct{}{}
+	defer func() { <-c.sem }()
+	return fs.ReadFile(c.fsys, file)
+}
+
+// readDir reads dir, waiting for a free worker.
+func (c *cache) readDir(dir string) ([]fs.DirEntry, error) {
+	c.sem <- struct{}{}
+	defer func() { <-c.sem }()
+	return fs.ReadDir(c.fsys, dir)
+}
+
+// scan runs fn in a new goroutine of wg if a worker is free, in the calling
+// goroutine otherwise. The goroutines are bounded and a scan never waits
+// for a worker held by the scan of a parent directory.
+func (c *cache) scan(wg *sync.WaitGroup, fn func()) {
+	select {
+	case c.workers <- struct{}{}:
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-c.workers }()
+			fn()
+		}()
+	default:
+		fn()
+	}
+}
+
+// lookup returns the value of key in m, computing it with fn on a miss.
+// Concurrent misses may compute the value more than once.
+func lookup[T any](c *cache, m map[string]T, key string, fn func() T) T {
+	c.mu.Lock()
+	v, ok := m[key]
+	c.mu.Unlock()
+	if ok {
+		return v
+	}
+	v = fn()
+	c.mu.Lock()
+	m[key] = v
+	c.mu.Unlock()
+	return v
+}
+
+func (c *cache) file(file string) *fileInfo {
+	return lookup(c, c.files, file, func() *fileInfo {
+		return c.readFileInfo(file)
+	})
+}
+
+func (c *cache) readFileInfo(file string) *fileInfo {
+	info := &fileInfo{title: path.Base(file), meta: &frontMatter{}}
+	if !slices.Contains(mdExts, path.Ext(file)) {
+		return info
+	}
+
+	b, err := c.readFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		// a missing index file is titled after its directory
+		info.title = path.Base(path.Dir(file))
+		return info
+	}
+	if err != nil {
+		info.err = newError("read file", file, err)
+		return info
+	}
+
+	content, _ := normalizeEOL(string(b))
+	block, body := splitNavFrontMatter(content)
+	info.meta = parseFrontMatterBlock(block)
+	if info.meta.Title != "" {
+		info.title = info.meta.Title
+		return info
+	}
+	s := bufio.NewScanner(strings.NewReader(body))
+	for s.Scan() {
+		cut, ok := strings.CutPrefix(s.Text(), "# ")
+		if ok && len(cut) > 0 {
+			info.title = strings.TrimSpace(cut)
+			break
+		}
+	}
+	return info
+}
+
+// readTitle returns the title of file: `title` in front matter, its
+// first-level title or its name.
+func (c *cache) readTitle(file string) string {
+	return c.file(file).title
+}
+
+func (c *cache) readFrontMatter(file string) *frontMatter {
+	return c.file(file).meta
+}
+
+// readDirConfig returns the config file of dir, empty if missing or invalid,
+// and the error reading an invalid one.
+func (c *cache) readDirConfig(dir string) (*Config, error) {
+	v := lookup(c, c.configs, dir, func() dirConfig {
+		cfg, err := readDirConfig(c.fsys, dir)
+		return dirConfig{cfg: cfg, err: err}
+	})
+	return v.cfg, v.err
+}
+
+// getIgnoreEntry returns the patterns of ignoreFile.
+func (c *cache) getIgnoreEntry(ignoreFile string) []string {
+	return lookup(c, c.ignores, ignoreFile, func() []string {
+		return getIgnoreEntry(c.fsys, ignoreFile)
+	})
+}
+
+// subRules returns the rules of the ignore files and config of dir,
+// matching the names of its files, compiled once per directory.
+func (c *cache) subRules(dir string) []excludeRule {
+	return lookup(c, c.rules, dir, func() []excludeRule {
+		return c.subExcludeRules(dir, "", nil)
+	})
+}
+
+// gitHistory returns the history of the repository containing dir, read
+// once per repository, or the history of the commit of a GitFS. It is
+// empty for the other filesystems.
+func (c *cache) gitHistory(dir string) gitHistory {
+	switch fsys := c.fsys.(type) {
+	case *GitFS:
+		return fsys.gitHistory()
+	case OSFS:
+	default:
+		return gitHistory{}
+	}
+	c.gitMu.Lock()
+	defer c.gitMu.Unlock()
+	root := gitRoot(dir)
+	if h, ok := c.histories[root]; ok {
+		return h
+	}
+	h := readGitHistory(dir)
+	c.histories[root] = h
+	return h
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// latencyFS delays every open of the embedded filesystem, like a network
+// filesystem, and records the peak number of goroutines.
+type latencyFS struct {
+	fs.FS
+	delay time.Duration
+
+	mu   sync.Mutex
+	peak int
+}
+
+func (l *latencyFS) Open(name string) (fs.File, error) {
+	l.mu.Lock()
+	l.peak = max(l.peak, runtime.NumGoroutine())
+	l.mu.Unlock()
+	time.Sleep(l.delay)
+	return l.FS.Open(name)
+}
+
+// syntheticTree writes dirs directories of files notes each, nested two
+// levels deep, with an ignore file every ten directories.
+func syntheticTree(tb testing.TB, dirs, files int) string {
+	dir := tb.TempDir()
+	tree := make(map[string]string)
+	for i := 0; i < dirs; i++ {
+		sub := fmt.Sprintf("d%02d/s%03d", i%10, i)
+		for j := 0; j < files; j++ {
+			tree[fmt.Sprintf("%s/n%03d.md", sub, j)] = fmt.Sprintf("---\nweight: %d\n---\n# Note %d-%d\n\nbody\n", files-j, i, j)
+		}
+		if i%10 == 0 {
+			tree[sub+"/.mdiignore"] = "n000.md\n"
+		}
+	}
+	writeTree(tb, dir, tree)
+	return dir
+}
+
+func TestNewIndexWorkers(t *testing.T) {
+	dir := syntheticTree(t, 40, 5)
+	newIdx := func(workers int) *IndexDump {
+		idx, err := NewIndex(&IndexOption{WorkDir: dir, RootIndexFile: path.Join(dir, defaultIndexFile), SubIndexFile: defaultIndexFile, cache: newCache(OSFS{}, workers)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return idx.Dump(true)
+	}
+
+	serial := newIdx(1)
+	for i := 0; i < 5; i++ {
+		if parallel := newIdx(0); !reflect.DeepEqual(parallel, serial) {
+			t.Fatalf("NewIndex() with %d workers = %+v, expected %+v", defaultWorkers, parallel, serial)
+		}
+	}
+}
+
+// denyFS fails to open the denied files of the embedded filesystem.
+type denyFS struct {
+	fs.FS
+	denied map[string]bool
+}
+
+func (d denyFS) Open(name string) (fs.File, error) {
+	if d.denied[name] {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return d.FS.Open(name)
+}
+
+func TestNewIndexReadError(t *testing.T) {
+	m := NewMemFS(map[string]string{
+		"go/hello.md":              "# Hello\n",
+		"go/secret.md":             "# Secret\n",
+		"rust/zz_generated_mdi.md": "# Rust\n",
+		"rust/intro.md":            "# Intro\n",
+	})
+	fsys := denyFS{FS: m, denied: map[string]bool{"go/secret.md": true, "rust/zz_generated_mdi.md": true}}
+	idx, err := NewIndex(&IndexOption{FS: fsys, WorkDir: ".", RootIndexFile: "README.md", SubIndexFile: defaultIndexFile})
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Fatalf("NewIndex() = %v, expected a permission error", err)
+	}
+	for _, file := range []string{"go/secret.md", "rust/zz_generated_mdi.md"} {
+		if !strings.Contains(err.Error(), file) {
+			t.Errorf("NewIndex() = %v, expected the error to name %s", err, file)
+		}
+	}
+	if idx == nil || len(idx.children) != 2 || len(idx.children[0].entries) != 1 {
+		t.Errorf("NewIndex() = %+v, expected the readable files to be indexed", idx)
+	}
+}
+
+func TestNewIndexBoundedGoroutines(t *testing.T) {
+	dir := syntheticTree(t, 100, 3)
+	fsys := &latencyFS{FS: OSFS{}, delay: 100 * time.Microsecond}
+	base := runtime.NumGoroutine()
+	if _, err := NewIndex(&IndexOption{WorkDir: dir, RootIndexFile: path.Join(dir, defaultIndexFile), SubIndexFile: defaultIndexFile, cache: newCache(fsys, 4)}); err != nil {
+		t.Fatal(err)
+	}
+	if fsys.peak > base+4 {
+		t.Errorf("NewIndex() with 4 workers ran %d goroutines, expected at most %d", fsys.peak, base+4)
+	}
+}
+
+// BenchmarkNewIndex reads the tree from the page cache, where the scan is
+// bound by the CPU, and with a delay per open, where it waits on I/O.
+func BenchmarkNewIndex(b *testing.B) {
+	dir := syntheticTree(b, 200, 20)
+	for _, delay := range []time.Duration{0, 100 * time.Microsecond} {
+		for _, workers := range []int{1, defaultWorkers} {
+			b.Run(fmt.Sprintf("delay=%s/workers=%d", delay, workers), func(b *testing.B) {
+				var fsys fs.FS = OSFS{}
+				if delay > 0 {
+					fsys = &latencyFS{FS: fsys, delay: delay}
+				}
+				for i := 0; i < b.N; i++ {
+					if _, err := NewIndex(&IndexOption{WorkDir: dir, RootIndexFile: path.Join(dir, defaultIndexFile), SubIndexFile: defaultIndexFile, cache: newCache(fsys, workers)}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFile is the name of the mdi config file looked up in every directory.
+const ConfigFile = ".mdi.yaml"
+
+// Config is the content of a config file. The config file of the work dir
+// fills IndexOption and GenerationOption, config files in subdirectories
+// only override Title, Exclude, Order and Sort of their subtree.
+type Config struct {
+	Title   string   `yaml:"title"`
+	Exclude []string `yaml:"exclude"`
+	Order   []string `yaml:"order"`
+	Sort    string   `yaml:"sort"`
+
+	IndexTitle       string `yaml:"index-title"`
+	HomeTitle        string `yaml:"home-title"`
+	RootIndexFile    string `yaml:"root-index-file"`
+	SubIndexFile     string `yaml:"sub-index-file"`
+	TemplateDir      string `yaml:"template-dir"`
+	Format           string `yaml:"format"`
+	Output           string `yaml:"output"`
+	InheritGitIgnore *bool  `yaml:"inherit-gitignore"`
+	Override         *bool  `yaml:"override"`
+	Recursive        *bool  `yaml:"recursive"`
+	NoHeaderLink     *bool  `yaml:"no-header-link"`
+	Nav              *bool  `yaml:"nav"`
+	Verbose          *bool  `yaml:"verbose"`
+	TOC              *bool  `yaml:"toc"`
+	TOCMinDepth      int    `yaml:"toc-min-depth"`
+	TOCMaxDepth      int    `yaml:"toc-max-depth"`
+	LastUpdated      *bool  `yaml:"last-updated"`
+	Recent           int    `yaml:"recent"`
+	Tags             *bool  `yaml:"tags"`
+	Backlinks        *bool  `yaml:"backlinks"`
+}
+
+// LoadConfig reads a config file, a missing file results in nil config and no error.
+func LoadConfig(file string) (*Config, error) {
+	return LoadConfigFS(OSFS{}, file)
+}
+
+// LoadConfigFS is LoadConfig on the filesystem fsys.
+func LoadConfigFS(fsys fs.FS, file string) (*Config, error) {
+	b, err := fs.ReadFile(fsys, file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, newError("read file", file, err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, newError("parse config", file, err)
+	}
+	return cfg, nil
+}
+
+// readDirConfig reads the config file of dir, empty if missing or invalid.
+func readDirConfig(fsys fs.FS, dir string) (*Config, error) {
+	cfg, err := LoadConfigFS(fsys, path.Join(dir, ConfigFile))
+	if err != nil || cfg == nil {
+		return &Config{}, err
+	}
+	return cfg, nil
+}
+
+// sortByOrder moves the items named in order to the front, in that order,
+// and keeps the original order of the others.
+func sortByOrder[T any](items []T, order []string, name func(T) string) {
+	if len(order) == 0 {
+		return
+	}
+	slices.SortStableFunc(items, func(a, b T) int {
+		return orderRank(order, name(a)) - orderRank(order, name(b))
+	})
+}
+
+// orderRank is the position of name in order, items not listed come last.
+func orderRank(order []string, name string) int {
+	i := slices.IndexFunc(order, func(o string) bool {
+		return strings.TrimSuffix(o, "/") == name
+	})
+	if i < 0 {
+		return len(order)
+	}
+	return i
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"errors"
+	"os"
+	"path"
+	"slices"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	if cfg, err := LoadConfig(path.Join(dir, ConfigFile)); cfg != nil || err != nil {
+		t.Fatalf("LoadConfig(missing) = %v, %v, expected nil, nil", cfg, err)
+	}
+
+	content := "title: Team Handbook\nnav: true\nexclude:\n  - drafts\norder: [intro.md, go/]\n"
+	if err := os.WriteFile(path.Join(dir, ConfigFile), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path.Join(dir, ConfigFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Title != "Team Handbook" || cfg.Nav == nil || !*cfg.Nav || cfg.Override != nil {
+		t.Errorf("LoadConfig() = %+v, unexpected content", cfg)
+	}
+	if !slices.Equal(cfg.Exclude, []string{"drafts"}) || !slices.Equal(cfg.Order, []string{"intro.md", "go/"}) {
+		t.Errorf("LoadConfig() = %+v, unexpected exclude or order", cfg)
+	}
+}
+
+func TestInvalidDirConfig(t *testing.T) {
+	m := NewMemFS(map[string]string{
+		"go/hello.md":   "# Hello\n",
+		"go/.mdi.yaml":  "title: [Golang\n",
+		"rust/intro.md": "# Intro\n",
+	})
+	idx, err := NewIndex(&IndexOption{FS: m, WorkDir: ".", RootIndexFile: "README.md", SubIndexFile: defaultIndexFile})
+	var e *Error
+	if !errors.As(err, &e) || e.Path != "go/.mdi.yaml" || e.Op != "parse config" {
+		t.Fatalf("NewIndex() = %v, expected a parse error on go/.mdi.yaml", err)
+	}
+	if idx == nil || len(idx.children) != 2 || idx.children[0].title != "go" {
+		t.Errorf("NewIndex() = %+v, expected the tree to be indexed without the invalid config", idx)
+	}
+
+	if _, err := LoadConfigFS(m, "go/.mdi.yaml"); !errors.As(err, &e) || e.Path != "go/.mdi.yaml" {
+		t.Errorf("LoadConfigFS(%q) = %v, expected an error on the file", "go/.mdi.yaml", err)
+	}
+}
+
+func TestSortByOrder(t *testing.T) {
+	items := []string{"a.md", "b.md", "c.md", "go", "rust"}
+	sortByOrder(items, []string{"rust/", "c.md", "missing.md"}, func(s string) string { return s })
+
+	expected := []string{"rust", "c.md", "a.md", "b.md", "go"}
+	if !slices.Equal(items, expected) {
+		t.Errorf("sortByOrder() = %v, expected %v", items, expected)
+	}
+}
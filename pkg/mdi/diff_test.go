@@ -0,0 +1,67 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	testdata := []struct {
+		name     string
+		before   string
+		after    string
+		kind     ChangeKind
+		expected string
+	}{
+		{
+			name:     "created",
+			after:    "# Title\n\ntext\n",
+			kind:     Created,
+			expected: "--- /dev/null\n+++ b/a.md\n@@ -0,0 +1,3 @@\n+# Title\n+\n+text\n",
+		},
+		{
+			name:     "deleted",
+			before:   "# Title\n\ntext\n",
+			kind:     Deleted,
+			expected: "--- a/a.md\n+++ /dev/null\n@@ -1,3 +0,0 @@\n-# Title\n-\n-text\n",
+		},
+		{
+			name:     "insert nav",
+			before:   "# Title\n\ntext\n",
+			after:    "[Home](README.md) / Title\n\n# Title\n\ntext\n",
+			expected: "--- a/a.md\n+++ b/a.md\n@@ -1,3 +1,5 @@\n+[Home](README.md) / Title\n+\n # Title\n \n text\n",
+		},
+		{
+			name:     "separate hunks",
+			before:   "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			after:    "0\n2\n3\n4\n5\n6\n7\n8\n9\n11\n",
+			expected: "--- a/a.md\n+++ b/a.md\n@@ -1,4 +1,4 @@\n-1\n+0\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+11\n",
+		},
+		{
+			name:     "no newline at end of file",
+			before:   "a\n",
+			after:    "a\nb",
+			expected: "--- a/a.md\n+++ b/a.md\n@@ -1 +1,2 @@\n a\n+b\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, d := range testdata {
+		actual := unifiedDiff("a.md", []byte(d.before), []byte(d.after), d.kind)
+		if actual != d.expected {
+			t.Errorf("%s: unifiedDiff() = %q, expected %q", d.name, actual, d.expected)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+// IndexDump is the serializable form of an index, built by Index.Dump.
+// Files are relative to the work dir of the root index.
+type IndexDump struct {
+	Title string `json:"title" yaml:"title"`
+	Dir   string `json:"dir" yaml:"dir"`
+	File  string `json:"file" yaml:"file"`
+	// Breadcrumbs are the parent indexes, from the root index down.
+	Breadcrumbs []*Ref       `json:"breadcrumbs" yaml:"breadcrumbs"`
+	Children    []*IndexDump `json:"children" yaml:"children"`
+	Entries     []*EntryDump `json:"entries" yaml:"entries"`
+	Skipped     []*Skip      `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+}
+
+// EntryDump is the serializable form of an entry.
+type EntryDump struct {
+	Title    string `json:"title" yaml:"title"`
+	NavTitle string `json:"nav_title" yaml:"nav_title"`
+	File     string `json:"file" yaml:"file"`
+	// Breadcrumbs are the indexes from the root index down to the index
+	// listing the entry.
+	Breadcrumbs []*Ref `json:"breadcrumbs" yaml:"breadcrumbs"`
+	Prev        *Ref   `json:"prev,omitempty" yaml:"prev,omitempty"`
+	Next        *Ref   `json:"next,omitempty" yaml:"next,omitempty"`
+}
+
+// Ref refers to an index or an entry of a dump.
+type Ref struct {
+	Title string `json:"title" yaml:"title"`
+	File  string `json:"file" yaml:"file"`
+}
+
+// Dump returns the serializable tree of idx, with the skipped paths of
+// every index if skipped is true.
+func (idx *Index) Dump(skipped bool) *IndexDump {
+	root := idx.Root()
+	d := &IndexDump{
+		Title:       idx.title,
+		Dir:         root.relPath(idx.workDir),
+		File:        idx.RelPath(),
+		Breadcrumbs: refs(idx.chains[:len(idx.chains)-1]),
+		Children:    make([]*IndexDump, 0, len(idx.children)),
+		Entries:     make([]*EntryDump, 0, len(idx.entries)),
+	}
+	if skipped {
+		d.Skipped = idx.Skipped()
+	}
+	for _, child := range idx.children {
+		d.Children = append(d.Children, child.Dump(skipped))
+	}
+	for _, e := range idx.entries {
+		ed := &EntryDump{
+			Title:       e.title,
+			NavTitle:    e.navTitle(),
+			File:        e.RelPath(),
+			Breadcrumbs: refs(idx.chains),
+		}
+		if e.prev != nil {
+			ed.Prev = &Ref{Title: e.prev.navTitle(), File: e.prev.RelPath()}
+		}
+		if e.next != nil {
+			ed.Next = &Ref{Title: e.next.navTitle(), File: e.next.RelPath()}
+		}
+		d.Entries = append(d.Entries, ed)
+	}
+	return d
+}
+
+func refs(indexes []*Index) []*Ref {
+	result := make([]*Ref, 0, len(indexes))
+	for _, idx := range indexes {
+		result = append(result, &Ref{Title: idx.navTitle(), File: idx.RelPath()})
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"path"
+	"testing"
+)
+
+func TestSkipped(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".mdiignore":           "private\n",
+		".gitignore":           "*.tmp.md\n!keep.tmp.md\n",
+		"root.md":              "# Root\n",
+		"draft.md":             "---\ndraft: true\n---\n# Draft\n",
+		"scratch.tmp.md":       "# Scratch\n",
+		"keep.tmp.md":          "# Keep\n",
+		"private/secret.md":    "# Secret\n",
+		"assets/logo.txt":      "logo",
+		"vendor/lib.md":        "# Lib\n",
+		"go/hello.md":          "# Hello\n",
+		"go/.mdi.yaml":         "exclude:\n  - wip.md\n",
+		"go/wip.md":            "# WIP\n",
+		"go/old/.mdiignore":    "*\n",
+		"go/old/legacy.md":     "# Legacy\n",
+		"go/hidden.md":         "---\nmdi_ignore: true\n---\n",
+		"go/.gitignore":        "notes.txt\n",
+		"go/notes.txt":         "not markdown",
+		"go/basics/intro.md":   "# Intro\n",
+		"go/basics/.mdiignore": "/intro.md\n",
+	})
+	idx, err := NewIndex(&IndexOption{WorkDir: dir, RootIndexFile: path.Join(dir, "README.md"), SubIndexFile: defaultIndexFile, InheritGitIgnore: true, Excludes: []string{"vendor"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]Skip{
+		"assets":         {Reason: SkipNoMarkdown},
+		"draft.md":       {Reason: SkipFrontMatter, Rule: "draft: true"},
+		"private":        {Reason: SkipIgnored, Rule: "private", Source: ".mdiignore"},
+		"scratch.tmp.md": {Reason: SkipIgnored, Rule: "*.tmp.md", Source: ".gitignore"},
+		"vendor":         {Reason: SkipIgnored, Rule: "vendor", Source: ExcludeSource},
+		"go/basics":      {Reason: SkipNoMarkdown},
+		"go/hidden.md":   {Reason: SkipFrontMatter, Rule: "mdi_ignore: true"},
+		"go/old":         {Reason: SkipNoMarkdown},
+		"go/wip.md":      {Reason: SkipIgnored, Rule: "wip.md", Source: "go/.mdi.yaml"},
+	}
+	actual := make(map[string]Skip)
+	idx.Walk(func(i *Index) error {
+		for _, s := range i.Skipped() {
+			actual[s.Path] = Skip{Reason: s.Reason, Rule: s.Rule, Source: s.Source}
+		}
+		return nil
+	})
+	for p, e := range expected {
+		if actual[p] != e {
+			t.Errorf("Skipped() of %s = %+v, expected %+v", p, actual[p], e)
+		}
+	}
+	for p := range actual {
+		if _, ok := expected[p]; !ok {
+			t.Errorf("Skipped() has %s, expected it indexed", p)
+		}
+	}
+}
+
+func TestDump(t *testing.T) {
+	idx := newTestIndex(t, map[string]string{
+		"root.md":     "# Root\n",
+		"go/hello.md": "# Hello\n",
+		"go/vars.md":  "---\nnav_title: Vars\n---\n# Variables\n",
+	})
+	d := idx.Dump(false)
+	if d.Title != "Notes" || d.Dir != "." || d.File != "README.md" || len(d.Breadcrumbs) != 0 || d.Skipped != nil {
+		t.Errorf("Dump() = %+v, unexpected root", d)
+	}
+	if len(d.Children) != 1 || len(d.Entries) != 1 || d.Entries[0].File != "root.md" {
+		t.Fatalf("Dump() = %+v, expected 1 child and root.md", d)
+	}
+
+	goDump := d.Children[0]
+	if goDump.Dir != "go" || goDump.File != "go/"+defaultIndexFile || len(goDump.Breadcrumbs) != 1 || goDump.Breadcrumbs[0].File != "README.md" {
+		t.Errorf("Dump() child = %+v, unexpected", goDump)
+	}
+	hello, vars := goDump.Entries[0], goDump.Entries[1]
+	if hello.Prev != nil || hello.Next == nil || *hello.Next != (Ref{Title: "Vars", File: "go/vars.md"}) {
+		t.Errorf("Dump() entry = %+v, expected next vars", hello)
+	}
+	if vars.NavTitle != "Vars" || vars.Title != "Variables" || len(vars.Breadcrumbs) != 2 || vars.Breadcrumbs[1].Title != "go" {
+		t.Errorf("Dump() entry = %+v, unexpected", vars)
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// ErrNotDir is returned when the work dir is not a directory.
+var ErrNotDir = errors.New("not a directory")
+
+// ErrReadOnly is returned when writing to a filesystem not implementing WriteFS.
+var ErrReadOnly = errors.New("read-only filesystem")
+
+// Error records a failed operation on a path. Functions failing on several
+// paths return every Error joined with errors.Join, use errors.As to
+// inspect them.
+type Error struct {
+	Op   string
+	Path string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return "failed to " + e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(op, path string, err error) error {
+	// the path is already recorded
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		err = pe.Err
+	}
+	return &Error{Op: op, Path: path, Err: err}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"path"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// Reasons of a Skip.
+const (
+	// SkipIgnored is a path matching an ignore pattern.
+	SkipIgnored = "ignored"
+	// SkipFrontMatter is a markdown file marked `draft` or `mdi_ignore`, or a
+	// page generated by mdi.
+	SkipFrontMatter = "front matter"
+	// SkipNoMarkdown is a directory without markdown files.
+	SkipNoMarkdown = "no markdown"
+)
+
+// ExcludeSource is the Source of the skips matching IndexOption.Excludes.
+const ExcludeSource = "exclude"
+
+// Skip is a path of a directory left out of its index.
+type Skip struct {
+	// Path is relative to the work dir of the root index.
+	Path   string `json:"path" yaml:"path"`
+	Reason string `json:"reason" yaml:"reason"`
+	// Rule is the ignore pattern or the front matter key excluding the path.
+	Rule string `json:"rule,omitempty" yaml:"rule,omitempty"`
+	// Source is the file defining the ignore pattern, relative to the work
+	// dir of the root index, or ExcludeSource.
+	Source string `json:"source,omitempty" yaml:"source,omitempty"`
+}
+
+// excludeRule is an ignore pattern and the file defining it.
+type excludeRule struct {
+	pattern  string
+	source   string
+	compiled gitignore.Pattern
+}
+
+// newExcludeRules parses patterns of source, scoped to domain.
+func newExcludeRules(patterns []string, source string, domain []string) []excludeRule {
+	rules := make([]excludeRule, 0, len(patterns))
+	for _, p := range patterns {
+		rules = append(rules, excludeRule{pattern: p, source: source, compiled: gitignore.ParsePattern(p, domain)})
+	}
+	return rules
+}
+
+// subExcludeRules returns the rules of the ignore files and config of
+// subDir, sources are relative to the dir rel.
+func (c *cache) subExcludeRules(subDir, rel string, domain []string) []excludeRule {
+	var result []excludeRule
+	// sub .mdiignore
+	result = append(result, newExcludeRules(c.getIgnoreEntry(path.Join(subDir, ".mdiignore")), path.Join(rel, ".mdiignore"), domain)...)
+	// sub .gitignore
+	result = append(result, newExcludeRules(c.getIgnoreEntry(path.Join(subDir, ".gitignore")), path.Join(rel, ".gitignore"), domain)...)
+	// sub .mdi.yaml, an invalid one is reported by the scan of subDir
+	cfg, _ := c.readDirConfig(subDir)
+	result = append(result, newExcludeRules(cfg.Exclude, path.Join(rel, ConfigFile), domain)...)
+	return result
+}
+
+// matchRule returns the last rule matching path, as gitignore does, nil if
+// none matches or if the last match is a negated pattern.
+func matchRule(rules []excludeRule, path []string, isDir bool) *excludeRule {
+	for i := len(rules) - 1; i >= 0; i-- {
+		switch rules[i].compiled.Match(path, isDir) {
+		case gitignore.Exclude:
+			return &rules[i]
+		case gitignore.Include:
+			return nil
+		}
+	}
+	return nil
+}
+
+func rulePatterns(rules []excludeRule) []string {
+	result := make([]string, 0, len(rules))
+	for _, r := range rules {
+		result = append(result, r.pattern)
+	}
+	return result
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of the generation. Every format but FormatMarkdown
+// exports the whole tree to a single output file.
+const (
+	// FormatMarkdown writes an index file in every directory.
+	FormatMarkdown = "markdown"
+	// FormatSummary writes an mdBook/GitBook SUMMARY.md.
+	FormatSummary = "summary"
+	// FormatMkDocs writes the `nav` of an MkDocs config, other keys of an
+	// existing config are kept.
+	FormatMkDocs = "mkdocs"
+	// FormatDocusaurus writes a Docusaurus sidebars file, JSON or, if the
+	// output file ends with `.js`, a CommonJS module.
+	FormatDocusaurus = "docusaurus"
+)
+
+// Default output files of the formats, in the work dir.
+const (
+	SummaryFile    = "SUMMARY.md"
+	MkDocsFile     = "mkdocs.yml"
+	DocusaurusFile = "sidebars.json"
+)
+
+var formats = []string{FormatMarkdown, FormatSummary, FormatMkDocs, FormatDocusaurus}
+
+// ValidateFormat checks the format option, empty is FormatMarkdown.
+func ValidateFormat(format string) error {
+	if format != "" && !slices.Contains(formats, format) {
+		return fmt.Errorf("invalid format: %s, available formats: %s", format, strings.Join(formats, ", "))
+	}
+	return nil
+}
+
+// OutputFile returns the file written by the format of genOpt for the work
+// dir, empty for FormatMarkdown.
+func (genOpt *GenerationOption) OutputFile(workDir string) string {
+	if genOpt.Output != "" {
+		return genOpt.Output
+	}
+	switch genOpt.Format {
+	case FormatSummary:
+		return path.Join(workDir, SummaryFile)
+	case FormatMkDocs:
+		return path.Join(workDir, MkDocsFile)
+	case FormatDocusaurus:
+		return path.Join(workDir, DocusaurusFile)
+	}
+	return ""
+}
+
+func (idx *Index) planExport(p *Plan, genOpt *GenerationOption) error {
+	file := genOpt.OutputFile(idx.workDir)
+	existing, err := fs.ReadFile(idx.fsys, file)
+	if err == nil && !genOpt.Override {
+		if genOpt.Verbose {
+			fmt.Printf("SKIP: output file conflict: %s, use --override=true to override it\n", file)
+		}
+		return nil
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return newError("read file", file, err)
+	}
+
+	var content []byte
+	switch genOpt.Format {
+	case FormatSummary:
+		content, err = []byte(idx.renderSummary()), nil
+	case FormatMkDocs:
+		content, err = idx.renderMkDocs(existing)
+	case FormatDocusaurus:
+		content, err = idx.renderDocusaurus(path.Ext(file) == ".js")
+	}
+	if err != nil {
+		return newError("render", file, err)
+	}
+	return p.add(file, content)
+}
+
+// renderMkDocs renders the `nav` of an MkDocs config, replacing the nav of
+// the existing config if any. Sub indexes are sections starting with
+// their index file, in the same order as the index.
+func (idx *Index) renderMkDocs(existing []byte) ([]byte, error) {
+	page := func(title, file string) *yaml.Node {
+		return mappingNode(title, &yaml.Node{Kind: yaml.ScalarNode, Value: idx.relPath(file)})
+	}
+	var section func(subIdx *Index) *yaml.Node
+	section = func(subIdx *Index) *yaml.Node {
+		items := &yaml.Node{Kind: yaml.SequenceNode}
+		if idx.exists(subIdx.file) && subIdx != idx {
+			items.Content = append(items.Content, page(subIdx.title, subIdx.file))
+		}
+		for _, child := range subIdx.children {
+			items.Content = append(items.Content, mappingNode(child.title, section(child)))
+		}
+		for _, entry := range subIdx.entries {
+			items.Content = append(items.Content, page(entry.title, entry.file))
+		}
+		return items
+	}
+	nav := section(idx)
+	if idx.exists(idx.file) {
+		nav.Content = append([]*yaml.Node{page(idx.title, idx.file)}, nav.Content...)
+	}
+
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	if len(bytes.TrimSpace(existing)) > 0 {
+		if err := yaml.Unmarshal(existing, doc); err != nil {
+			return nil, err
+		}
+		if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("not a mapping")
+		}
+	}
+	root := doc.Content[0]
+	replaced := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "nav" {
+			root.Content[i+1] = nav
+			replaced = true
+		}
+	}
+	if !replaced {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "nav"}, nav)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func mappingNode(key string, value *yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: key}, value}}
+}
+
+// sidebarItem is an item of a Docusaurus sidebar, a doc or a category.
+type sidebarItem struct {
+	Type  string         `json:"type"`
+	ID    string         `json:"id,omitempty"`
+	Label string         `json:"label,omitempty"`
+	Link  *sidebarItem   `json:"link,omitempty"`
+	Items []*sidebarItem `json:"items,omitempty"`
+}
+
+// renderDocusaurus renders a Docusaurus sidebars file with a single `docs`
+// sidebar. Sub indexes are categories linking to their index file, in the
+// same order as the index.
+func (idx *Index) renderDocusaurus(js bool) ([]byte, error) {
+	doc := func(title, file string) *sidebarItem {
+		return &sidebarItem{Type: "doc", ID: docID(idx.relPath(file)), Label: title}
+	}
+	var items func(subIdx *Index) []*sidebarItem
+	items = func(subIdx *Index) []*sidebarItem {
+		result := make([]*sidebarItem, 0, len(subIdx.children)+len(subIdx.entries))
+		for _, child := range subIdx.children {
+			category := &sidebarItem{Type: "category", Label: child.title, Items: items(child)}
+			if idx.exists(child.file) {
+				category.Link = &sidebarItem{Type: "doc", ID: docID(idx.relPath(child.file))}
+			}
+			result = append(result, category)
+		}
+		for _, entry := range subIdx.entries {
+			result = append(result, doc(entry.title, entry.file))
+		}
+		return result
+	}
+	sidebar := items(idx)
+	if idx.exists(idx.file) {
+		sidebar = append([]*sidebarItem{doc(idx.title, idx.file)}, sidebar...)
+	}
+
+	b, err := json.MarshalIndent(map[string][]*sidebarItem{"docs": sidebar}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if js {
+		return []byte("module.exports = " + string(b) + ";\n"), nil
+	}
+	return append(b, '\n'), nil
+}
+
+// numberPrefix is the number prefix Docusaurus strips from doc ids, as in `01-intro.md`.
+var numberPrefix = regexp.MustCompile(`^\d+\s*[-_.]+\s*([^-_.\s])`)
+
+// docID is the Docusaurus id of the doc of relPath: the path without
+// extension and number prefixes.
+func docID(relPath string) string {
+	parts := strings.Split(strings.TrimSuffix(relPath, path.Ext(relPath)), "/")
+	for i := range parts {
+		parts[i] = numberPrefix.ReplaceAllString(parts[i], "$1")
+	}
+	return strings.Join(parts, "/")
+}
+
+// exists reports whether file exists in the filesystem of the tree.
+func (idx *Index) exists(file string) bool {
+	_, err := fs.Stat(idx.fsys, file)
+	return err == nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"testing"
+)
+
+var exportTestFiles = map[string]string{
+	"README.md":              "# Notes\n",
+	"root.md":                "# Root\n",
+	"go/zz_generated_mdi.md": "# Go\n",
+	"go/hello.md":            "# Hello: World\n",
+	"go/basics/01-intro.md":  "# Intro\n",
+	"my notes/a note.md":     "# A Note\n",
+}
+
+func TestRenderMkDocs(t *testing.T) {
+	idx := newTestIndex(t, exportTestFiles)
+
+	nav := `nav:
+  - Notes: README.md
+  - Go:
+      - Go: go/zz_generated_mdi.md
+      - basics:
+          - Intro: go/basics/01-intro.md
+      - 'Hello: World': go/hello.md
+  - my notes:
+      - A Note: my notes/a note.md
+  - Root: root.md
+`
+	testdata := []struct {
+		existing string
+		expected string
+	}{
+		{"", nav},
+		{"site_name: Notes\n# theme of the site\ntheme:\n  name: material\nnav:\n  - old.md\nmarkdown_extensions:\n  - pymdownx.emoji:\n      emoji_generator: !!python/name:material.extensions.emoji.to_svg\n",
+			"site_name: Notes\n# theme of the site\ntheme:\n  name: material\n" + nav + "markdown_extensions:\n  - pymdownx.emoji:\n      emoji_generator: !!python/name:material.extensions.emoji.to_svg\n"},
+		{"site_name: Notes\n", "site_name: Notes\n" + nav},
+	}
+	for _, d := range testdata {
+		actual, err := idx.renderMkDocs([]byte(d.existing))
+		if err != nil || string(actual) != d.expected {
+			t.Errorf("renderMkDocs(%q) = %q, %v, expected %q", d.existing, actual, err, d.expected)
+		}
+	}
+
+	if _, err := idx.renderMkDocs([]byte("- not a mapping\n")); err == nil {
+		t.Errorf("renderMkDocs(sequence) = nil error, expected an error")
+	}
+}
+
+func TestRenderDocusaurus(t *testing.T) {
+	idx := newTestIndex(t, exportTestFiles)
+
+	sidebar := `{
+  "docs": [
+    {
+      "type": "doc",
+      "id": "README",
+      "label": "Notes"
+    },
+    {
+      "type": "category",
+      "label": "Go",
+      "link": {
+        "type": "doc",
+        "id": "go/zz_generated_mdi"
+      },
+      "items": [
+        {
+          "type": "category",
+          "label": "basics",
+          "items": [
+            {
+              "type": "doc",
+              "id": "go/basics/intro",
+              "label": "Intro"
+            }
+          ]
+        },
+        {
+          "type": "doc",
+          "id": "go/hello",
+          "label": "Hello: World"
+        }
+      ]
+    },
+    {
+      "type": "category",
+      "label": "my notes",
+      "items": [
+        {
+          "type": "doc",
+          "id": "my notes/a note",
+          "label": "A Note"
+        }
+      ]
+    },
+    {
+      "type": "doc",
+      "id": "root",
+      "label": "Root"
+    }
+  ]
+}`
+	testdata := []struct {
+		js       bool
+		expected string
+	}{
+		{false, sidebar + "\n"},
+		{true, "module.exports = " + sidebar + ";\n"},
+	}
+	for _, d := range testdata {
+		actual, err := idx.renderDocusaurus(d.js)
+		if err != nil || string(actual) != d.expected {
+			t.Errorf("renderDocusaurus(%v) = %s, %v, expected %s", d.js, actual, err, d.expected)
+		}
+	}
+}
+
+func TestDocID(t *testing.T) {
+	testdata := []struct {
+		relPath  string
+		expected string
+	}{
+		{"intro.md", "intro"},
+		{"01-intro.md", "intro"},
+		{"2_setup.markdown", "setup"},
+		{"10 - guides/3.usage.md", "guides/usage"},
+		{"2023.md", "2023"},
+		{"v1-notes/1-2-3.md", "v1-notes/2-3"},
+	}
+	for _, d := range testdata {
+		if actual := docID(d.relPath); actual != d.expected {
+			t.Errorf("docID(%q) = %q, expected %q", d.relPath, actual, d.expected)
+		}
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	testdata := []struct {
+		format   string
+		expected bool
+	}{
+		{"", true},
+		{FormatMarkdown, true},
+		{FormatSummary, true},
+		{FormatMkDocs, true},
+		{FormatDocusaurus, true},
+		{"hugo", false},
+	}
+	for _, d := range testdata {
+		if actual := ValidateFormat(d.format) == nil; actual != d.expected {
+			t.Errorf("ValidateFormat(%q) valid = %v, expected %v", d.format, actual, d.expected)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatter holds the YAML front matter keys honored by mdi.
+type frontMatter struct {
+	Title    string  `yaml:"title"`
+	NavTitle string  `yaml:"nav_title"`
+	Weight   *int    `yaml:"weight"`
+	Order    *int    `yaml:"order"`
+	Draft    bool    `yaml:"draft"`
+	Ignore   bool    `yaml:"mdi_ignore"`
+	Tags     tagList `yaml:"tags"`
+	// Generated marks the pages written by mdi outside of the index tree.
+	Generated bool `yaml:"mdi_generated"`
+}
+
+// generatedRule is the front matter key of the pages written by mdi.
+const generatedRule = "mdi_generated: true"
+
+// excluded reports whether the file should be left out of indexes and nav.
+func (fm *frontMatter) excluded() bool {
+	return fm.excludeRule() != ""
+}
+
+// excludeRule returns the key excluding the file, empty if it is not.
+func (fm *frontMatter) excludeRule() string {
+	switch {
+	case fm.Draft:
+		return "draft: true"
+	case fm.Ignore:
+		return "mdi_ignore: true"
+	case fm.Generated:
+		return generatedRule
+	}
+	return ""
+}
+
+// tagList is the `tags` key, a list or a string of comma or space
+// separated tags.
+type tagList []string
+
+func (l *tagList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = strings.FieldsFunc(value.Value, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+		return nil
+	}
+	var tags []string
+	if err := value.Decode(&tags); err != nil {
+		return err
+	}
+	*l = tags
+	return nil
+}
+
+// weight returns the `weight` or `order` key, ok is false if neither is set.
+func (fm *frontMatter) weight() (int, bool) {
+	if fm.Weight != nil {
+		return *fm.Weight, true
+	}
+	if fm.Order != nil {
+		return *fm.Order, true
+	}
+	return 0, false
+}
+
+// sortByWeight moves the items having a weight to the front, lightest
+// first, and keeps the original order of the others.
+func sortByWeight[T any](items []T, meta func(T) *frontMatter) {
+	slices.SortStableFunc(items, func(a, b T) int {
+		return compareWeight(meta(a), meta(b))
+	})
+}
+
+// compareWeight compares weights, items without a weight come last.
+func compareWeight(a, b *frontMatter) int {
+	wa, oka := a.weight()
+	wb, okb := b.weight()
+	switch {
+	case oka && okb:
+		return wa - wb
+	case oka:
+		return -1
+	case okb:
+		return 1
+	}
+	return 0
+}
+
+// splitFrontMatter splits content into its front matter block, fences
+// included, and the body following it.
+func splitFrontMatter(content string) (string, string) {
+	first, rest, ok := strings.Cut(content, "\n")
+	if !ok || strings.TrimRight(first, " \r") != "---" {
+		return "", content
+	}
+
+	offset := len(first) + 1
+	for rest != "" {
+		line, next, _ := strings.Cut(rest, "\n")
+		offset += len(line) + 1
+		if l := strings.TrimRight(line, " \r"); l == "---" || l == "..." {
+			offset = min(offset, len(content))
+			// a document opening with a horizontal rule is not front matter
+			var m map[string]any
+			yml := frontMatterYAML(content[:offset])
+			if yaml.Unmarshal([]byte(yml), &m) != nil || (m == nil && strings.TrimSpace(yml) != "") {
+				return "", content
+			}
+			return content[:offset], content[offset:]
+		}
+		rest = next
+	}
+	return "", content
+}
+
+func parseFrontMatter(content string) *frontMatter {
+	block, _ := splitFrontMatter(content)
+	return parseFrontMatterBlock(block)
+}
+
+// parseFrontMatterBlock parses a front matter block split from a file.
+func parseFrontMatterBlock(block string) *frontMatter {
+	fm := &frontMatter{}
+	if block == "" {
+		return fm
+	}
+
+	if err := yaml.Unmarshal([]byte(frontMatterYAML(block)), fm); err != nil {
+		return &frontMatter{}
+	}
+	return fm
+}
+
+// frontMatterYAML drops the fences of a front matter block.
+func frontMatterYAML(block string) string {
+	_, yml, _ := strings.Cut(block, "\n")
+	if i := strings.LastIndex(strings.TrimRight(yml, "\r\n"), "\n"); i >= 0 {
+		return yml[:i]
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	testdata := []struct {
+		content string
+		fm      string
+		body    string
+	}{
+		{"---\ntitle: Hello\n---\n# Hello\n", "---\ntitle: Hello\n---\n", "# Hello\n"},
+		{"---\r\ntitle: Hello\r\n---\r\n# Hello\r\n", "---\r\ntitle: Hello\r\n---\r\n", "# Hello\r\n"},
+		{"---\ntitle: Hello\n...\n", "---\ntitle: Hello\n...\n", ""},
+		{"---\n---\nbody", "---\n---\n", "body"},
+		{"# Hello\n---\ntitle: Hello\n---\n", "", "# Hello\n---\ntitle: Hello\n---\n"},
+		{"---\n\nhorizontal rule first\n\n---\n", "", "---\n\nhorizontal rule first\n\n---\n"},
+		{"---\n# not yaml\n---\n", "", "---\n# not yaml\n---\n"},
+		{"---\ntitle: unclosed\n", "", "---\ntitle: unclosed\n"},
+	}
+
+	for _, d := range testdata {
+		fm, body := splitFrontMatter(d.content)
+		if fm != d.fm || body != d.body {
+			t.Errorf("splitFrontMatter(%q) = %q, %q, expected %q, %q", d.content, fm, body, d.fm, d.body)
+		}
+	}
+}
+
+func TestParseFrontMatter(t *testing.T) {
+	fm := parseFrontMatter("---\ntitle: Setup\nnav_title: Setup\norder: 2\ndraft: true\n---\n# Other\n")
+	if fm.Title != "Setup" || fm.NavTitle != "Setup" || !fm.excluded() {
+		t.Errorf("parseFrontMatter() = %+v, unexpected keys", fm)
+	}
+	if w, ok := fm.weight(); !ok || w != 2 {
+		t.Errorf("weight() = %d, %v, expected 2, true", w, ok)
+	}
+
+	fm = parseFrontMatter("# Title\n")
+	if fm.Title != "" || fm.excluded() {
+		t.Errorf("parseFrontMatter() = %+v, expected empty front matter", fm)
+	}
+	if _, ok := fm.weight(); ok {
+		t.Errorf("weight() is set without front matter")
+	}
+}
+
+func TestParseFrontMatterTags(t *testing.T) {
+	testdata := []struct {
+		content  string
+		expected []string
+	}{
+		{"---\ntags: [go, cli]\n---\n", []string{"go", "cli"}},
+		{"---\ntags:\n  - go\n  - lang/go\n---\n", []string{"go", "lang/go"}},
+		{"---\ntags: go, cli\n---\n", []string{"go", "cli"}},
+		{"---\ntags: go cli\n---\n", []string{"go", "cli"}},
+		{"---\ntitle: Hello\n---\n", nil},
+	}
+
+	for _, d := range testdata {
+		if fm := parseFrontMatter(d.content); !slices.Equal(fm.Tags, d.expected) {
+			t.Errorf("parseFrontMatter(%q).Tags = %q, expected %q", d.content, fm.Tags, d.expected)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/poneding/mdi/pkg/util"
+)
+
+// WriteFS is a filesystem the generated files are written to. Indexes read
+// from a filesystem not implementing it, like an embed.FS or a zip.Reader,
+// can be planned and exported but not generated.
+type WriteFS interface {
+	fs.FS
+	// WriteFile writes data to the file name, its parent directories are
+	// created if missing.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	// Remove removes the file name.
+	Remove(name string) error
+}
+
+// OSFS is the filesystem of the host, the default one. Unlike os.DirFS, its
+// names are paths as accepted by package os: relative to the current
+// directory, or absolute.
+type OSFS struct{}
+
+func (OSFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (OSFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (OSFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (OSFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (OSFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, perm)
+}
+
+func (OSFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+// DirFS is the tree of files rooted at a directory of the host, like
+// os.DirFS, and writable.
+type DirFS string
+
+func (d DirFS) Open(name string) (fs.File, error) {
+	return os.DirFS(string(d)).Open(name)
+}
+
+func (d DirFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	return OSFS{}.WriteFile(filepath.Join(string(d), filepath.FromSlash(name)), data, perm)
+}
+
+func (d DirFS) Remove(name string) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+	return os.Remove(filepath.Join(string(d), filepath.FromSlash(name)))
+}
+
+// MemFS is an in-memory filesystem, safe for concurrent use. Its
+// directories are implied by the files they contain.
+type MemFS struct {
+	mu    sync.RWMutex
+	files map[string]*memFileInfo
+}
+
+// NewMemFS returns a MemFS holding files, mapping slash-separated paths to
+// their content.
+func NewMemFS(files map[string]string) *MemFS {
+	m := &MemFS{files: make(map[string]*memFileInfo, len(files))}
+	for name, content := range files {
+		m.files[name] = &memFileInfo{name: path.Base(name), data: []byte(content), mode: 0644}
+	}
+	return m
+}
+
+func (m *MemFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if f, ok := m.files[name]; ok {
+		return &memFile{info: f, Reader: bytes.NewReader(f.data)}, nil
+	}
+	d := &memDir{info: &memFileInfo{name: path.Base(name), mode: fs.ModeDir | 0555}}
+	prefix := util.If(name == ".", "", name+"/")
+	seen := make(map[string]bool)
+	for file, f := range m.files {
+		rest, ok := strings.CutPrefix(file, prefix)
+		if !ok {
+			continue
+		}
+		child, _, isDir := strings.Cut(rest, "/")
+		if seen[child] {
+			continue
+		}
+		seen[child] = true
+		if isDir {
+			f = &memFileInfo{name: child, mode: fs.ModeDir | 0555}
+		}
+		d.entries = append(d.entries, fs.FileInfoToDirEntry(f))
+	}
+	if len(d.entries) == 0 && name != "." {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	slices.SortFunc(d.entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return d, nil
+}
+
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// written files are replaced, not modified, open ones keep their content
+	m.files[name] = &memFileInfo{name: path.Base(name), data: bytes.Clone(data), mode: perm, modTime: time.Now()}
+	return nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[name]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m.files, name)
+	return nil
+}
+
+// memFileInfo is a file of a MemFS, with its content, or an implied
+// directory.
+type memFileInfo struct {
+	name    string
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (fi *memFileInfo) Name() string       { return fi.name }
+func (fi *memFileInfo) Size() int64        { return int64(len(fi.data)) }
+func (fi *memFileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi *memFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *memFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *memFileInfo) Sys() any           { return nil }
+
+type memFile struct {
+	info *memFileInfo
+	*bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+type memDir struct {
+	info    *memFileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	rest = rest[:min(n, len(rest))]
+	d.offset += len(rest)
+	return rest, nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMemFS(t *testing.T) {
+	m := NewMemFS(map[string]string{"go/hello.md": "# Hello\n", "old.md": "# Old\n"})
+	if err := m.WriteFile("rust/intro.md", []byte("# Intro\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Remove("old.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(m, "go/hello.md", "rust/intro.md"); err != nil {
+		t.Error(err)
+	}
+
+	if err := m.Remove("old.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Remove(%q) = %v, expected a not exist error", "old.md", err)
+	}
+	if err := m.WriteFile("../up.md", nil, 0644); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("WriteFile(%q) = %v, expected an invalid error", "../up.md", err)
+	}
+}
+
+func TestGenerateMemFS(t *testing.T) {
+	m := NewMemFS(map[string]string{
+		"go/hello.md":     "# Hello\n",
+		"go/draft.md":     "---\ndraft: true\n---\n# Draft\n",
+		"go/.mdi.yaml":    "title: Golang\n",
+		"rust/intro.md":   "# Intro\n",
+		"private/todo.md": "# Todo\n",
+		".mdiignore":      "private\n",
+	})
+	idxOpt := &IndexOption{FS: m, WorkDir: ".", IndexTitle: "Notes", RootIndexFile: "README.md", SubIndexFile: defaultIndexFile}
+	idx, err := NewIndex(idxOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Generate(&GenerationOption{Override: true, Recursive: true, Nav: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	testdata := []struct {
+		file     string
+		expected string
+	}{
+		{"README.md", "## [Golang](go/zz_generated_mdi.md)"},
+		{"go/zz_generated_mdi.md", "[Hello](hello.md)"},
+		{"go/hello.md", NavStart},
+		{"rust/intro.md", NavStart},
+	}
+	for _, d := range testdata {
+		b, err := fs.ReadFile(m, d.file)
+		if err != nil || !strings.Contains(string(b), d.expected) {
+			t.Errorf("ReadFile(%q) = %q, %v, expected it to contain %q", d.file, b, err, d.expected)
+		}
+	}
+	for _, file := range []string{"private/zz_generated_mdi.md", "go/draft.md"} {
+		if b, _ := fs.ReadFile(m, file); strings.Contains(string(b), NavStart) {
+			t.Errorf("ReadFile(%q) = %q, expected no nav in excluded files", file, b)
+		}
+	}
+
+	if err := CleanFS(m, ".", defaultIndexFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(m, "go/zz_generated_mdi.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat(%q) = %v after CleanFS, expected a not exist error", "go/zz_generated_mdi.md", err)
+	}
+	if b, _ := fs.ReadFile(m, "go/hello.md"); string(b) != "# Hello\n" {
+		t.Errorf("ReadFile(%q) = %q after CleanFS, expected %q", "go/hello.md", b, "# Hello\n")
+	}
+}
+
+func TestGenerateReadOnlyFS(t *testing.T) {
+	fsys := fstest.MapFS{"go/hello.md": &fstest.MapFile{Data: []byte("# Hello\n")}}
+	idx, err := NewIndex(&IndexOption{FS: fsys, WorkDir: ".", RootIndexFile: "README.md", SubIndexFile: defaultIndexFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	genOpt := &GenerationOption{Override: true, Recursive: true}
+	p, err := idx.Plan(genOpt)
+	if err != nil || p.Count(Created) != 2 {
+		t.Errorf("Plan() = %d created files, %v, expected 2", p.Count(Created), err)
+	}
+	if err := idx.Generate(genOpt); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Generate() = %v, expected %v", err, ErrReadOnly)
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"errors"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitInfo is the last commit changing a file or a directory.
+type commitInfo struct {
+	When   time.Time
+	Author string
+}
+
+// gitHistory maps the files of a repository to their last commit.
+type gitHistory struct {
+	// root is the directory of the repository on the host, empty if the
+	// files are names of a GitFS.
+	root string
+	// files maps slash-separated paths relative to the root of the
+	// repository to their last commit.
+	files map[string]*commitInfo
+}
+
+// openRepo opens the repository containing dir, or the bare repository dir.
+// root is the directory of its worktree, or dir if it is bare.
+func openRepo(dir string) (repo *git.Repository, root string, err error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	if repo, err = git.PlainOpen(abs); err != nil {
+		if repo, err = git.PlainOpenWithOptions(abs, &git.PlainOpenOptions{DetectDotGit: true}); err != nil {
+			return nil, "", err
+		}
+	}
+	wt, err := repo.Worktree()
+	if errors.Is(err, git.ErrIsBareRepository) {
+		return repo, abs, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return repo, wt.Filesystem.Root(), nil
+}
+
+// gitRoot returns the root of the repository containing dir, empty if dir
+// is not in a git repository.
+func gitRoot(dir string) string {
+	_, root, _ := openRepo(dir)
+	return root
+}
+
+// readGitHistory returns the history of HEAD of the repository containing
+// dir. It is empty if dir is not in a git repository.
+func readGitHistory(dir string) gitHistory {
+	repo, root, err := openRepo(dir)
+	if err != nil {
+		return gitHistory{}
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return gitHistory{root: root}
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return gitHistory{root: root}
+	}
+	history := walkHistory(commit)
+	history.root = root
+	return history
+}
+
+// walkHistory computes the history of commit in a single walk over its
+// first-parent history.
+func walkHistory(commit *object.Commit) gitHistory {
+	history := gitHistory{files: make(map[string]*commitInfo)}
+	var err error
+	for err == nil && commit != nil {
+		var changes object.Changes
+		var parent *object.Commit
+		if changes, parent, err = firstParentChanges(commit); err != nil {
+			break
+		}
+
+		info := &commitInfo{When: commit.Committer.When, Author: commit.Author.Name}
+		for _, c := range changes {
+			name := c.To.Name
+			if name == "" {
+				// deleted file, still bump its directories
+				name = c.From.Name
+			}
+			for p := name; p != "." && p != "/"; p = path.Dir(p) {
+				if _, ok := history.files[p]; ok {
+					break
+				}
+				history.files[p] = info
+			}
+		}
+		commit = parent
+	}
+	return history
+}
+
+// firstParentChanges returns the changes of commit against its first
+// parent, parent is nil for the root commit.
+func firstParentChanges(commit *object.Commit) (object.Changes, *object.Commit, error) {
+	var parent *object.Commit
+	var parentTree *object.Tree
+	if commit.NumParents() > 0 {
+		var err error
+		if parent, err = commit.Parent(0); err != nil {
+			return nil, nil, err
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return nil, nil, err
+		}
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, nil, err
+	}
+	changes, err := object.DiffTree(parentTree, tree)
+	return changes, parent, err
+}
+
+// lastCommit returns the last commit of file, nil if it was never committed.
+func (h gitHistory) lastCommit(file string) *commitInfo {
+	if h.root != "" {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(h.root, abs)
+		if err != nil {
+			return nil
+		}
+		file = filepath.ToSlash(rel)
+	}
+	return h.files[path.Clean(file)]
+}
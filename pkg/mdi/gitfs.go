@@ -0,0 +1,229 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// GitFS is the read-only filesystem of the tree of a git commit, its names
+// are paths relative to the root of the repository. The files are read
+// from the objects of the repository, a bare clone is enough.
+type GitFS struct {
+	// mu serializes the reads of the repository objects.
+	mu     sync.Mutex
+	repo   *git.Repository
+	commit *object.Commit
+	tree   *object.Tree
+	root   string
+
+	historyOnce sync.Once
+	history     gitHistory
+}
+
+// NewGitFS opens the tree of the revision rev, like a branch, a tag or a
+// commit hash, of the repository containing dir or of the bare repository
+// dir.
+func NewGitFS(dir, rev string) (*GitFS, error) {
+	repo, root, err := openRepo(dir)
+	if err != nil {
+		return nil, newError("open repository", dir, err)
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, newError("resolve revision", rev, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, newError("read commit", rev, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, newError("read tree", rev, err)
+	}
+	return &GitFS{repo: repo, commit: commit, tree: tree, root: root}, nil
+}
+
+// Root returns the directory of the repository on the host, the names of
+// g are relative to it.
+func (g *GitFS) Root() string {
+	return g.root
+}
+
+func (g *GitFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if name == "." {
+		return g.openDir(name, g.tree), nil
+	}
+	entry, err := g.tree.FindEntry(name)
+	if err != nil || entry.Mode == filemode.Submodule {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if entry.Mode == filemode.Dir {
+		tree, err := g.tree.Tree(name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		return g.openDir(name, tree), nil
+	}
+
+	b, err := g.readBlob(entry.Hash)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &gitFile{info: g.info(path.Base(name), int64(len(b)), false), Reader: bytes.NewReader(b)}, nil
+}
+
+func (g *GitFS) readBlob(hash plumbing.Hash) ([]byte, error) {
+	blob, err := g.repo.BlobObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (g *GitFS) openDir(name string, tree *object.Tree) *gitDir {
+	d := &gitDir{info: g.info(path.Base(name), 0, true)}
+	for _, e := range tree.Entries {
+		if e.Mode == filemode.Submodule {
+			continue
+		}
+		d.entries = append(d.entries, &gitDirEntry{g: g, name: e.Name, dir: e.Mode == filemode.Dir, hash: e.Hash})
+	}
+	return d
+}
+
+// info describes the files of g, modified at the time of its commit.
+func (g *GitFS) info(name string, size int64, dir bool) *gitFileInfo {
+	return &gitFileInfo{name: name, size: size, dir: dir, modTime: g.commit.Committer.When}
+}
+
+// gitHistory returns the history of the commit of g, walked once.
+func (g *GitFS) gitHistory() gitHistory {
+	g.historyOnce.Do(func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.history = walkHistory(g.commit)
+	})
+	return g.history
+}
+
+type gitFileInfo struct {
+	name    string
+	size    int64
+	dir     bool
+	modTime time.Time
+}
+
+func (fi *gitFileInfo) Name() string       { return fi.name }
+func (fi *gitFileInfo) Size() int64        { return fi.size }
+func (fi *gitFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *gitFileInfo) IsDir() bool        { return fi.dir }
+func (fi *gitFileInfo) Sys() any           { return nil }
+
+func (fi *gitFileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+type gitFile struct {
+	info *gitFileInfo
+	*bytes.Reader
+}
+
+func (f *gitFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *gitFile) Close() error               { return nil }
+
+type gitDir struct {
+	info    *gitFileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *gitDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *gitDir) Close() error               { return nil }
+
+func (d *gitDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+func (d *gitDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	rest = rest[:min(n, len(rest))]
+	d.offset += len(rest)
+	return rest, nil
+}
+
+// gitDirEntry is an entry of a tree, the size of files is read on Info.
+type gitDirEntry struct {
+	g    *GitFS
+	name string
+	dir  bool
+	hash plumbing.Hash
+}
+
+func (e *gitDirEntry) Name() string { return e.name }
+func (e *gitDirEntry) IsDir() bool  { return e.dir }
+
+func (e *gitDirEntry) Type() fs.FileMode {
+	if e.dir {
+		return fs.ModeDir
+	}
+	return 0
+}
+
+func (e *gitDirEntry) Info() (fs.FileInfo, error) {
+	if e.dir {
+		return e.g.info(e.name, 0, true), nil
+	}
+	e.g.mu.Lock()
+	defer e.g.mu.Unlock()
+	blob, err := e.g.repo.BlobObject(e.hash)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: e.name, Err: err}
+	}
+	return e.g.info(e.name, blob.Size, false), nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitTree writes files in the worktree of repo and commits them.
+func commitTree(t *testing.T, repo *git.Repository, dir string, files map[string]string, when time.Time) string {
+	t.Helper()
+	writeTree(t, dir, files)
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.AddGlob("."); err != nil {
+		t.Fatal(err)
+	}
+	sig := &object.Signature{Name: "alice", Email: "alice@example.com", When: when}
+	hash, err := wt.Commit("update", &git.CommitOptions{Author: sig, Committer: sig})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash.String()
+}
+
+func TestGitFS(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	rev := commitTree(t, repo, dir, map[string]string{
+		"docs/go/hello.md":   "# Hello\n",
+		"docs/go/.mdi.yaml":  "title: Golang\n",
+		"docs/private/x.md":  "# X\n",
+		"docs/.mdiignore":    "private\n",
+		"docs/rust/intro.md": "# Intro\n",
+	}, first)
+	commitTree(t, repo, dir, map[string]string{"docs/go/hello.md": "# Hi\n"}, first.Add(time.Hour))
+	// not committed
+	writeTree(t, dir, map[string]string{"docs/go/new.md": "# New\n"})
+
+	g, err := NewGitFS(path.Join(dir, "docs"), rev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(g, "docs/go/hello.md", "docs/go/.mdi.yaml", "docs/rust/intro.md"); err != nil {
+		t.Error(err)
+	}
+
+	idx, err := NewIndex(&IndexOption{FS: g, WorkDir: "docs", RootIndexFile: "docs/README.md", SubIndexFile: defaultIndexFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	idx.Walk(func(i *Index) error {
+		if i != idx {
+			titles = append(titles, i.Title())
+		}
+		for _, e := range i.Entries() {
+			titles = append(titles, e.Title())
+		}
+		return nil
+	})
+	if expected := []string{"Golang", "Hello", "rust", "Intro"}; !slices.Equal(titles, expected) {
+		t.Errorf("NewIndex() at %s has titles %v, expected %s", rev, titles, expected)
+	}
+	if c := newCache(g, 0).gitHistory("docs").lastCommit("docs/go/hello.md"); c == nil || !c.When.Equal(first) {
+		t.Errorf("lastCommit(%q) = %+v at %s, expected %s", "docs/go/hello.md", c, rev, first)
+	}
+
+	genOpt := &GenerationOption{Override: true, Recursive: true}
+	if err := idx.Generate(genOpt); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Generate() = %v, expected %v", err, ErrReadOnly)
+	}
+	p, err := idx.Plan(genOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	if err := p.ApplyTo(DirFS(out), genOpt); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := fs.ReadFile(DirFS(out), "docs/go/zz_generated_mdi.md"); err != nil || !strings.Contains(string(b), "[Hello](hello.md)") {
+		t.Errorf("ApplyTo() wrote %q, %v, expected the index of %s", b, err, rev)
+	}
+}
+
+func TestGitFSBare(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitTree(t, repo, dir, map[string]string{"go/hello.md": "# Hello\n"}, time.Now())
+
+	bare := t.TempDir()
+	if _, err := git.PlainClone(bare, true, &git.CloneOptions{URL: dir}); err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGitFS(bare, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Root() != bare {
+		t.Errorf("Root() = %q, expected %q", g.Root(), bare)
+	}
+	if b, err := fs.ReadFile(g, "go/hello.md"); err != nil || string(b) != "# Hello\n" {
+		t.Errorf("ReadFile(%q) = %q, %v, expected %q", "go/hello.md", b, err, "# Hello\n")
+	}
+
+	if _, err := NewGitFS(bare, "missing"); err == nil {
+		t.Errorf("NewGitFS(%q) = nil error, expected an error", "missing")
+	}
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// LinkIssue is a broken link found by LintLinks.
+type LinkIssue struct {
+	File string
+	// Line is 1-based, in the file with its front matter.
+	Line   int
+	Link   string
+	Reason string
+}
+
+func (i *LinkIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", i.File, i.Line, i.Reason, i.Link)
+}
+
+// mdLink is a link or an image of a markdown file.
+type mdLink struct {
+	dest string
+	line int
+}
+
+// mdDoc is what the lint reads of a markdown file.
+type mdDoc struct {
+	links   []mdLink
+	anchors map[string]bool
+}
+
+type linter struct {
+	root *Index
+	md   goldmark.Markdown
+	docs map[string]*mdDoc
+	// excluded are the skipped paths of the tree, relative to the work dir.
+	excluded map[string]*Skip
+}
+
+// LintLinks checks the relative links and images of the index files and
+// entries of the tree: the target must exist, an `#anchor` must match a
+// heading of the target, and a markdown target must not be excluded from
+// the tree. External and absolute links are not checked. Files failing to
+// be read are reported in the returned error.
+func (idx *Index) LintLinks() ([]*LinkIssue, error) {
+	l := &linter{
+		root:     idx,
+		md:       newMarkdown(),
+		docs:     make(map[string]*mdDoc),
+		excluded: make(map[string]*Skip),
+	}
+	var files []string
+	idx.Walk(func(i *Index) error {
+		for _, s := range i.skipped {
+			if s.Reason != SkipNoMarkdown {
+				l.excluded[s.Path] = s
+			}
+		}
+		if idx.exists(i.file) {
+			files = append(files, i.file)
+		}
+		for _, e := range i.entries {
+			files = append(files, e.file)
+		}
+		return nil
+	})
+
+	var issues []*LinkIssue
+	var errs []error
+	for _, file := range files {
+		doc, err := l.doc(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, link := range doc.links {
+			if reason := l.check(file, link.dest); reason != "" {
+				issues = append(issues, &LinkIssue{File: file, Line: link.line, Link: link.dest, Reason: reason})
+			}
+		}
+	}
+	return issues, errors.Join(errs...)
+}
+
+// check returns why the link dest of file is broken, empty if it is not.
+func (l *linter) check(file, dest string) string {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return "invalid link"
+	}
+	if u.Scheme != "" || u.Host != "" || path.IsAbs(u.Path) {
+		return ""
+	}
+
+	target := file
+	if u.Path != "" {
+		target = path.Join(path.Dir(file), u.Path)
+		fi, err := fs.Stat(l.root.fsys, target)
+		if err != nil {
+			return "file not found"
+		}
+		if fi.IsDir() || !isMarkdown(target) {
+			return ""
+		}
+		// the generated tag pages are out of the tree but not broken
+		if s := l.excludedBy(target); s != nil && s.Rule != generatedRule {
+			if s.Reason == SkipFrontMatter {
+				return "excluded by front matter " + s.Rule
+			}
+			return fmt.Sprintf("excluded by %s in %s", s.Rule, s.Source)
+		}
+	}
+
+	if u.Fragment != "" && isMarkdown(target) {
+		doc, err := l.doc(target)
+		if err != nil {
+			return "file not found"
+		}
+		if !doc.anchors[strings.ToLower(u.Fragment)] {
+			return "anchor not found"
+		}
+	}
+	return ""
+}
+
+// excludedBy returns the skip of file or of its closest excluded parent dir.
+func (l *linter) excludedBy(file string) *Skip {
+	rel := l.root.relPath(file)
+	for rel != "." && rel != "/" && rel != ".." && !strings.HasPrefix(rel, "../") {
+		if s, ok := l.excluded[rel]; ok {
+			return s
+		}
+		rel = path.Dir(rel)
+	}
+	return nil
+}
+
+// doc parses file, once.
+func (l *linter) doc(file string) (*mdDoc, error) {
+	if doc, ok := l.docs[file]; ok {
+		return doc, nil
+	}
+	b, err := fs.ReadFile(l.root.fsys, file)
+	if err != nil {
+		return nil, newError("read file", file, err)
+	}
+	l.docs[file] = parseDoc(l.md, string(b))
+	return l.docs[file], nil
+}
+
+// parseDoc reads the links and heading anchors of the markdown content.
+func parseDoc(md goldmark.Markdown, content string) *mdDoc {
+	fm, body := splitFrontMatter(content)
+	offset := strings.Count(fm, "\n")
+	src := []byte(body)
+
+	doc := &mdDoc{anchors: make(map[string]bool)}
+	slugs := make(map[string]int)
+	root := md.Parser().Parse(text.NewReader(src))
+	ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Heading:
+			slug := githubSlug(nodeText(n, src))
+			if i := slugs[slug]; i > 0 {
+				doc.anchors[fmt.Sprintf("%s-%d", slug, i)] = true
+			} else {
+				doc.anchors[slug] = true
+			}
+			slugs[slug]++
+		case *ast.Link:
+			doc.links = append(doc.links, mdLink{dest: string(n.Destination), line: offset + nodeLine(n, src)})
+		case *ast.Image:
+			doc.links = append(doc.links, mdLink{dest: string(n.Destination), line: offset + nodeLine(n, src)})
+		}
+		return ast.WalkContinue, nil
+	})
+	return doc
+}
+
+// nodeLine returns the 1-based line of n in src, from its first text or
+// from the first line of its block.
+func nodeLine(n ast.Node, src []byte) int {
+	offset := -1
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if t, ok := c.(*ast.Text); ok && entering {
+			offset = t.Segment.Start
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	for p := n; offset < 0 && p != nil; p = p.Parent() {
+		if p.Type() == ast.TypeBlock && p.Lines().Len() > 0 {
+			offset = p.Lines().At(0).Start
+		}
+	}
+	return bytes.Count(src[:max(offset, 0)], []byte("\n")) + 1
+}
+
+// nodeText returns the plain text of n.
+func nodeText(n ast.Node, src []byte) string {
+	var sb strings.Builder
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch c := c.(type) {
+		case *ast.Text:
+			sb.Write(c.Segment.Value(src))
+		case *ast.String:
+			sb.Write(c.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return sb.String()
+}
+
+// githubSlug returns the anchor of a heading as GitHub renders it: lower
+// case, punctuation removed and spaces replaced with hyphens.
+func githubSlug(heading string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(heading)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || r == '-' || r == '_':
+			sb.WriteRune(r)
+		case r == ' ':
+			sb.WriteByte('-')
+		}
+	}
+	return sb.String()
+}
+
+func isMarkdown(file string) bool {
+	return slices.Contains(mdExts, path.Ext(file))
+}
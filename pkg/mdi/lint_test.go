@@ -0,0 +1,90 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"testing"
+)
+
+func TestLintLinks(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".mdiignore": "private\n",
+		"root.md": "---\ntitle: Root\n---\n# Root\n\n" +
+			"[ok](go/hello.md) [ok](go/hello.md#hello-world) [ok](<my notes/a note.md>) [ok](my%20notes/a%20note.md)\n" +
+			"[missing](go/gone.md) [anchor](go/hello.md#nope)\n" +
+			"[ok](https://example.com/x.md) [ok](/abs.md) [ok](mailto:me@example.com) [ok](go/)\n",
+		"go/hello.md": "# Hello, World!\n\n## Setup\n\n## Setup\n\n" +
+			"[ok](#setup) [ok](#setup-1) [ok](#Hello-World) [broken](#setup-2)\n\n" +
+			"```\n[in code](gone.md)\n```\n\n" +
+			"![ok](logo.png) ![missing](img/none.png)\n" +
+			"[private](../private/todo.md) [draft](draft.md) [ok](../assets/logo.txt)\n",
+		"go/logo.png":        "PNG",
+		"go/draft.md":        "---\ndraft: true\n---\n# Draft\n",
+		"my notes/a note.md": "# A Note\n\n[back][root]\n\n[root]: ../root.md#nope\n",
+		"private/todo.md":    "# Todo\n\n[not linted](gone.md)\n",
+		"assets/logo.txt":    "logo",
+	})
+	idx, err := NewIndex(&IndexOption{WorkDir: dir, RootIndexFile: path.Join(dir, "README.md"), SubIndexFile: defaultIndexFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := idx.LintLinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []string
+	for _, i := range issues {
+		actual = append(actual, fmt.Sprintf("%s:%d: %s: %s", idx.relPath(i.File), i.Line, i.Reason, i.Link))
+	}
+	expected := []string{
+		"root.md:7: file not found: go/gone.md",
+		"root.md:7: anchor not found: go/hello.md#nope",
+		"go/hello.md:7: anchor not found: #setup-2",
+		"go/hello.md:13: file not found: img/none.png",
+		"go/hello.md:14: excluded by private in .mdiignore: ../private/todo.md",
+		"go/hello.md:14: excluded by front matter draft: true: draft.md",
+		"my notes/a note.md:3: anchor not found: ../root.md#nope",
+	}
+	if !slices.Equal(actual, expected) {
+		t.Errorf("LintLinks() = %q, expected %q", actual, expected)
+	}
+}
+
+func TestGithubSlug(t *testing.T) {
+	testdata := []struct {
+		heading  string
+		expected string
+	}{
+		{"Hello World", "hello-world"},
+		{"Hello, World!", "hello-world"},
+		{"  Trim me ", "trim-me"},
+		{"snake_case and kebab-case", "snake_case-and-kebab-case"},
+		{"Go 1.21 (released)", "go-121-released"},
+		{"中文 标题", "中文-标题"},
+		{"a  b", "a--b"},
+	}
+	for _, d := range testdata {
+		if actual := githubSlug(d.heading); actual != d.expected {
+			t.Errorf("githubSlug(%q) = %q, expected %q", d.heading, actual, d.expected)
+		}
+	}
+}
@@ -18,15 +18,17 @@ package mdi
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"path"
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 
-	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
 	"github.com/poneding/mdi/pkg/util"
+	"github.com/yuin/goldmark"
 )
 
 var defaultIndexFile = "zz_generated_mdi.md"
@@ -45,15 +47,23 @@ var defaultIndexOption = &IndexOption{
 // 	Nav:       false,
 // }
 
-type index struct {
+// Index is a directory of the index tree, with its sub indexes and its
+// markdown entries, built by NewIndex.
+type Index struct {
 	workDir   string
 	file      string
 	title     string
 	homeTitle string
+	meta      *frontMatter
 	// content  string
-	chains   []*index
-	children []*index
-	entries  []*entry
+	chains   []*Index
+	children []*Index
+	entries  []*Entry
+	skipped  []*Skip
+	// fsys is the filesystem of the tree.
+	fsys fs.FS
+	// cache holds the lookups of the run building the tree.
+	cache *cache
 }
 
 type IndexOption struct {
@@ -63,9 +73,23 @@ type IndexOption struct {
 	RootIndexFile    string
 	SubIndexFile     string
 	InheritGitIgnore bool
-	chains           []*index
-	rootExcludes     *[]string
-	subExcludes      []string
+	// Excludes are extra ignore patterns appended to `.mdiignore`.
+	Excludes []string
+	// Order lists the file and directory names listed first, in that order.
+	Order []string
+	// Sort is the comma separated list of sort keys, DefaultSort if empty.
+	Sort string
+	// FS is the filesystem the tree is read from, and written to if it
+	// implements WriteFS. The paths of the options are names of FS, OSFS if
+	// nil.
+	FS           fs.FS
+	chains       []*Index
+	rootExcludes *[]excludeRule
+	rootDir      string
+	dirExcludes  []excludeRule
+	// cache is shared by the NewIndex calls of a run, a new one is made
+	// for the root index if nil.
+	cache *cache
 }
 
 type GenerationOption struct {
@@ -74,47 +98,97 @@ type GenerationOption struct {
 	Nav          bool
 	Verbose      bool
 	NoHeaderLink bool
+	// Templates render index files and nav, the built-in templates if nil.
+	Templates *Templates
+	// Format is one of the Format constants, FormatMarkdown if empty.
+	Format string
+	// Output is the file written by the formats exporting the whole tree,
+	// the default output file of the format in the work dir if empty.
+	Output string
+	// TOC inserts a table of contents of the headings between TOCMinDepth
+	// and TOCMaxDepth in entries, DefaultTOCMinDepth and DefaultTOCMaxDepth
+	// if zero.
+	TOC         bool
+	TOCMinDepth int
+	TOCMaxDepth int
+	// LastUpdated annotates the entries of index files with the date and
+	// author of their last commit.
+	LastUpdated bool
+	// Recent is the number of entries last changed in git listed at the
+	// top of the root index file, none if zero.
+	Recent int
+	// Tags writes the tags index TagsFile and a page per tag in TagsDir,
+	// in the work dir of the root index.
+	Tags bool
+	// Backlinks adds a section listing the entries linking to each entry.
+	Backlinks bool
 }
 
-type entry struct {
+// Entry is a markdown file listed in an index.
+type Entry struct {
 	title string
 	file  string
-	prev  *entry
-	next  *entry
+	meta  *frontMatter
+	index *Index
+	prev  *Entry
+	next  *Entry
+}
+
+// navTitle returns the title used in nav, `nav_title` in front matter if set.
+func (idx *Index) navTitle() string {
+	if idx.meta != nil && idx.meta.NavTitle != "" {
+		return idx.meta.NavTitle
+	}
+	return idx.title
+}
+
+func (e *Entry) navTitle() string {
+	if e.meta != nil && e.meta.NavTitle != "" {
+		return e.meta.NavTitle
+	}
+	return e.title
 }
 
 func (idxOpt *IndexOption) RootExcludes() []string {
+	return rulePatterns(idxOpt.rootRules())
+}
+
+func (idxOpt *IndexOption) filesystem() fs.FS {
+	if idxOpt.FS == nil {
+		return OSFS{}
+	}
+	return idxOpt.FS
+}
+
+func (idxOpt *IndexOption) rootRules() []excludeRule {
 	if idxOpt.rootExcludes == nil {
-		idxOpt.rootExcludes = &[]string{}
+		idxOpt.rootExcludes = &[]excludeRule{}
+		*idxOpt.rootExcludes = append(*idxOpt.rootExcludes, newExcludeRules(idxOpt.Excludes, ExcludeSource, nil)...)
+
 		// .mdiignore
-		*idxOpt.rootExcludes = append(*idxOpt.rootExcludes, getIgnoreEntry(path.Join(idxOpt.WorkDir, ".mdiignore"))...)
+		*idxOpt.rootExcludes = append(*idxOpt.rootExcludes, newExcludeRules(getIgnoreEntry(idxOpt.filesystem(), path.Join(idxOpt.WorkDir, ".mdiignore")), ".mdiignore", nil)...)
 
 		// .gitignore
 		if idxOpt.InheritGitIgnore {
-			*idxOpt.rootExcludes = append(*idxOpt.rootExcludes, getIgnoreEntry(path.Join(idxOpt.WorkDir, ".gitignore"))...)
+			*idxOpt.rootExcludes = append(*idxOpt.rootExcludes, newExcludeRules(getIgnoreEntry(idxOpt.filesystem(), path.Join(idxOpt.WorkDir, ".gitignore")), ".gitignore", nil)...)
 		}
 	}
-
-	// fmt.Printf("(*idxOpt.rootExcludes): %v\n", (*idxOpt.rootExcludes))
-
 	return *idxOpt.rootExcludes
 }
 
-func getSubExcludes(subDir string) []string {
-	var result []string
-	// sub .mdiignore
-	result = append(result, getIgnoreEntry(path.Join(subDir, ".mdiignore"))...)
-	// sub .gitignore
-	result = append(result, getIgnoreEntry(path.Join(subDir, ".gitignore"))...)
-	return result
-}
-
-func NewIndex(idxOpt *IndexOption) *index {
-	files, err := os.ReadDir(idxOpt.WorkDir)
+// getDirExcludes appends the sub excludes of subDir, scoped to subDir, to the inherited rules.
+func (c *cache) getDirExcludes(inherited []excludeRule, rootDir, subDir string) []excludeRule {
+	rel, err := filepath.Rel(rootDir, subDir)
 	if err != nil {
-		panic(err)
+		return inherited
 	}
+	rel = filepath.ToSlash(rel)
+	return append(slices.Clone(inherited), c.subExcludeRules(subDir, rel, strings.Split(rel, "/"))...)
+}
 
+// NewIndex builds the index tree of the work dir. Directories failing to
+// be read are left out of the tree and reported in the returned error.
+func NewIndex(idxOpt *IndexOption) (*Index, error) {
 	// validate opt
 	// if idxOpt == nil {
 	// 	idxOpt = defaultIndexOption
@@ -123,8 +197,18 @@ func NewIndex(idxOpt *IndexOption) *index {
 	if idxOpt.WorkDir == "" {
 		idxOpt.WorkDir = defaultIndexOption.WorkDir
 	}
-	if fi, err := os.Stat(idxOpt.WorkDir); os.IsNotExist(err) && !fi.IsDir() {
-		panic(fmt.Sprintf("invalid work dir: %s", idxOpt.WorkDir))
+	c := idxOpt.cache
+	if c == nil {
+		c = newCache(idxOpt.filesystem(), 0)
+	}
+	if fi, err := fs.Stat(c.fsys, idxOpt.WorkDir); err != nil {
+		return nil, newError("stat", idxOpt.WorkDir, err)
+	} else if !fi.IsDir() {
+		return nil, newError("index", idxOpt.WorkDir, ErrNotDir)
+	}
+	files, err := c.readDir(idxOpt.WorkDir)
+	if err != nil {
+		return nil, newError("read dir", idxOpt.WorkDir, err)
 	}
 	if idxOpt.IndexTitle == "" {
 		idxOpt.IndexTitle = defaultIndexOption.IndexTitle
@@ -132,55 +216,82 @@ func NewIndex(idxOpt *IndexOption) *index {
 	if idxOpt.HomeTitle == "" {
 		idxOpt.HomeTitle = idxOpt.IndexTitle
 	}
+	if idxOpt.rootDir == "" {
+		idxOpt.rootDir = idxOpt.WorkDir
+	}
 
 	// if idxOpt.SubIndexFile == "" {
 	// 	idxOpt.SubIndexFile = path.Join(idxOpt.WorkDir, defaultIndexFile)
 	// }
 
-	idx := &index{
+	idx := &Index{
 		workDir:   idxOpt.WorkDir,
 		file:      util.If(len(idxOpt.RootIndexFile) > 0, idxOpt.RootIndexFile, idxOpt.SubIndexFile),
 		title:     idxOpt.IndexTitle,
 		homeTitle: idxOpt.HomeTitle,
-		children:  make([]*index, 0),
-		entries:   make([]*entry, 0),
-	}
-	// set self as chain tail
-	idx.chains = append(idxOpt.chains, idx)
-
-	for _, f := range files {
+		meta:      c.readFrontMatter(util.If(len(idxOpt.RootIndexFile) > 0, idxOpt.RootIndexFile, idxOpt.SubIndexFile)),
+		children:  make([]*Index, 0),
+		entries:   make([]*Entry, 0),
+		fsys:      c.fsys,
+		cache:     c,
+	}
+	// set self as chain tail, sub indexes are built concurrently and must
+	// not share the backing array
+	idx.chains = append(slices.Clip(idxOpt.chains), idx)
+
+	// sub indexes and entries are read concurrently, then collected in the
+	// order of files
+	items := make([]scanItem, len(files))
+	rootRules := idxOpt.rootRules()
+	var wg sync.WaitGroup
+	for i, f := range files {
+		i := i
 		subFile := path.Join(idxOpt.WorkDir, f.Name())
-		if matchFile(idxOpt.RootExcludes(), subFile) {
+		rel, _ := filepath.Rel(idxOpt.rootDir, subFile)
+		rel = filepath.ToSlash(rel)
+		rule := matchRule(rootRules, strings.Split(subFile, "/"), true)
+		if rule == nil {
+			rule = matchRule(idxOpt.dirExcludes, strings.Split(rel, "/"), f.IsDir())
+		}
+		if rule != nil {
+			if f.IsDir() || slices.Contains(mdExts, path.Ext(f.Name())) {
+				items[i].skip = &Skip{Path: rel, Reason: SkipIgnored, Rule: rule.pattern, Source: rule.source}
+			}
 			continue
 		}
 
 		if f.IsDir() {
-			if hasMdFile(subFile, idxOpt.SubIndexFile) {
-				indexFile := path.Join(subFile, path.Base(idxOpt.SubIndexFile))
-				subIndexOpt := &IndexOption{
-					WorkDir:      subFile,
-					IndexTitle:   readTitle(indexFile),
-					HomeTitle:    idxOpt.HomeTitle,
-					SubIndexFile: indexFile,
-					rootExcludes: idxOpt.rootExcludes,
-					subExcludes:  getSubExcludes(subFile),
-					chains:       append(idxOpt.chains, idx), // append chains in sub index option
-				}
-				subIdx := NewIndex(subIndexOpt)
-				if subIdx != nil {
-					idx.children = append(idx.children, subIdx)
-				}
-			}
-		} else {
-			if slices.Contains(mdExts, path.Ext(f.Name())) && f.Name() != path.Base(idx.file) {
-				idx.entries = append(idx.entries, &entry{
-					title: readTitle(subFile),
-					file:  subFile,
-				})
-			}
+			c.scan(&wg, func() {
+				items[i] = idx.scanDir(idxOpt, c, subFile, rel)
+			})
+		} else if slices.Contains(mdExts, path.Ext(f.Name())) && f.Name() != path.Base(idx.file) {
+			c.scan(&wg, func() {
+				items[i] = idx.scanFile(c, subFile, rel)
+			})
+		}
+	}
+	wg.Wait()
+
+	// the index file is read for its title and front matter
+	errs := []error{c.file(idx.file).err}
+	for _, item := range items {
+		if item.err != nil {
+			errs = append(errs, item.err)
+		}
+		if item.skip != nil {
+			idx.skipped = append(idx.skipped, item.skip)
+		}
+		if item.child != nil {
+			idx.children = append(idx.children, item.child)
+		}
+		if item.entry != nil {
+			idx.entries = append(idx.entries, item.entry)
 		}
 	}
 
+	sortItems(c, idx.children, idxOpt.Sort, idxOpt.Order, indexSortItem)
+	sortItems(c, idx.entries, idxOpt.Sort, idxOpt.Order, entrySortItem)
+
 	for i := 0; i < len(idx.entries); i++ {
 		if i > 0 {
 			idx.entries[i].prev = idx.entries[i-1]
@@ -190,266 +301,264 @@ func NewIndex(idxOpt *IndexOption) *index {
 		}
 	}
 
-	return idx
+	return idx, errors.Join(errs...)
+}
+
+// scanItem is a file or directory of an index: a sub index, an entry or a
+// skipped path.
+type scanItem struct {
+	child *Index
+	entry *Entry
+	skip  *Skip
+	err   error
+}
+
+// scanDir builds the sub index of the directory subFile.
+func (idx *Index) scanDir(idxOpt *IndexOption, c *cache, subFile, rel string) scanItem {
+	ok, err := c.hasMdFile(subFile, idxOpt.SubIndexFile)
+	if err != nil {
+		return scanItem{err: err}
+	}
+	if !ok {
+		return scanItem{skip: &Skip{Path: rel, Reason: SkipNoMarkdown}}
+	}
+
+	indexFile := path.Join(subFile, path.Base(idxOpt.SubIndexFile))
+	subCfg, cfgErr := c.readDirConfig(subFile)
+	subIndexOpt := &IndexOption{
+		WorkDir:      subFile,
+		IndexTitle:   util.If(subCfg.Title != "", subCfg.Title, c.readTitle(indexFile)),
+		HomeTitle:    idxOpt.HomeTitle,
+		SubIndexFile: indexFile,
+		Order:        subCfg.Order,
+		Sort:         util.If(subCfg.Sort != "", subCfg.Sort, idxOpt.Sort),
+		rootExcludes: idxOpt.rootExcludes,
+		rootDir:      idxOpt.rootDir,
+		dirExcludes:  c.getDirExcludes(idxOpt.dirExcludes, idxOpt.rootDir, subFile),
+		chains:       idx.chains, // append chains in sub index option
+		FS:           c.fsys,
+		cache:        c,
+	}
+	subIdx, err := NewIndex(subIndexOpt)
+	return scanItem{child: subIdx, err: errors.Join(cfgErr, err)}
+}
+
+// scanFile reads the entry of the markdown file subFile.
+func (idx *Index) scanFile(c *cache, subFile, rel string) scanItem {
+	info := c.file(subFile)
+	if info.err != nil {
+		return scanItem{err: info.err}
+	}
+	meta := info.meta
+	if meta.excluded() {
+		return scanItem{skip: &Skip{Path: rel, Reason: SkipFrontMatter, Rule: meta.excludeRule()}}
+	}
+	return scanItem{entry: &Entry{
+		title: info.title,
+		file:  subFile,
+		meta:  meta,
+		index: idx,
+	}}
 }
 
-func (idx *index) Generate(genOpt *GenerationOption) {
+// Generate writes the index files and nav. Files failing to be read or
+// written are skipped and reported in the returned error.
+func (idx *Index) Generate(genOpt *GenerationOption) error {
+	p, err := idx.Plan(genOpt)
+	return errors.Join(err, p.Apply(genOpt))
+}
+
+// Plan collects the index files and nav-decorated entries that Generate
+// would write, without touching the disk.
+func (idx *Index) Plan(genOpt *GenerationOption) (*Plan, error) {
+	p := &Plan{fsys: idx.fsys}
+	err := idx.plan(p, genOpt)
+	return p, err
+}
+
+func (idx *Index) plan(p *Plan, genOpt *GenerationOption) error {
 	if idx == nil {
-		return
+		return nil
 	}
 
+	var errs []error
+	if genOpt.Backlinks && len(idx.chains) == 1 {
+		backlinks, err := idx.readBacklinks()
+		p.backlinks = backlinks
+		errs = append(errs, err)
+	}
 	for _, subIdx := range idx.children {
 		if genOpt.Recursive {
-			subIdx.Generate(genOpt)
+			errs = append(errs, subIdx.plan(p, genOpt))
 		}
 	}
-	content := parseContent(idx, &parseContentOption{
-		WorkDir:      idx.workDir,
-		Content:      "",
-		NoHeaderLink: genOpt.NoHeaderLink,
-	})
-
-	if genOpt.Override {
-		err := os.WriteFile(idx.file, []byte(fmt.Sprintf("%s# %s\n%s", idx.getIndexNav(), idx.title, content)), 0644)
+	if genOpt.Format != "" && genOpt.Format != FormatMarkdown {
+		// the export covers the whole tree, whatever the filter
+		if len(idx.chains) == 1 {
+			errs = append(errs, idx.planExport(p, genOpt))
+		}
+	} else if r, b, crlf := idx.indexRegion(); r.found() && p.wants(idx.file) {
+		// a hand-written index file, only the region is generated
+		content, err := idx.renderIndex(genOpt, true)
 		if err != nil {
-			fmt.Printf("ERROR: failed to write index file: %s", err)
-		} else {
-			if genOpt.Verbose {
-				fmt.Printf("OK: generated index file: %s\n", idx.file)
-			}
+			return errors.Join(append(errs, err)...)
 		}
+		errs = append(errs, p.add(idx.file, []byte(restoreEOL(r.replace(b, content), crlf))))
+	} else if genOpt.Override && p.wants(idx.file) {
+		content, err := idx.renderIndex(genOpt, false)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		// keep front matter of the existing index file
+		var fm string
+		if b, err := fs.ReadFile(idx.fsys, idx.file); err == nil {
+			fm, _ = splitFrontMatter(string(b))
+		}
+		errs = append(errs, p.add(idx.file, []byte(fm+content)))
 	} else {
-		if genOpt.Verbose {
+		if genOpt.Verbose && p.wants(idx.file) {
 			fmt.Printf("SKIP: index file conflict: %s, use --override=true to override it\n", idx.file)
 		}
 	}
 
-	if genOpt.Nav {
-		idx.decorateEntry()
+	if genOpt.Tags && len(idx.chains) == 1 && (genOpt.Format == "" || genOpt.Format == FormatMarkdown) {
+		// like the export, the tags cover the whole tree, whatever the filter
+		errs = append(errs, idx.planTags(p, genOpt))
 	}
-}
 
-func (idx *index) getIndexNav() string {
-	if len(idx.chains) <= 1 {
-		return ""
+	if genOpt.Nav || genOpt.TOC || genOpt.Backlinks {
+		errs = append(errs, idx.decorateEntry(p, genOpt))
 	}
-
-	var indexNav string
-	// index not included, so loop to len-1
-	for i := 0; i < len(idx.chains)-1; i++ {
-		title := util.If(i == 0, idx.homeTitle, idx.chains[i].title)
-		backpath := strings.Repeat("../", len(idx.chains)-i-1) + path.Base(idx.chains[i].file)
-		indexNav += fmt.Sprintf("[%s](%s) / ", title, getLink(backpath))
-	}
-	indexNav += idx.title + "\n\n"
-	return indexNav
+	return errors.Join(errs...)
 }
 
-func (idx *index) getEntryNavPrefix() string {
-	var navPrefix string
-	for i := 0; i < len(idx.chains); i++ {
-		title := util.If(i == 0, idx.homeTitle, idx.chains[i].title)
-		backpath := strings.Repeat("../", len(idx.chains)-i-1) + path.Base(idx.chains[i].file)
-		navPrefix += fmt.Sprintf("[%s](%s) / ", title, getLink(backpath))
+// decorateEntry adds the nav, the TOC and the backlinks of genOpt to the
+// entries.
+func (idx *Index) decorateEntry(p *Plan, genOpt *GenerationOption) error {
+	t, err := genOpt.templates()
+	if err != nil {
+		return err
 	}
-	return navPrefix
-}
+	md := newMarkdown()
+	minDepth, maxDepth := genOpt.tocDepth()
 
-func (idx *index) decorateEntry() {
+	var errs []error
 	for _, entry := range idx.entries {
-		if s, _ := filepath.Rel(idx.file, entry.file); s == "." {
+		// the backlinks of an entry depend on every entry, whatever the filter
+		if s, _ := filepath.Rel(idx.file, entry.file); s == "." || !(p.wants(entry.file) || genOpt.Backlinks) {
 			continue
 		}
-		b, err := os.ReadFile(entry.file)
-		if err == nil {
+		b, err := fs.ReadFile(idx.fsys, entry.file)
+		if err != nil {
+			errs = append(errs, newError("read file", entry.file, err))
+		} else {
 			if len(b) == 0 {
 				continue
 			}
 
-			lines := strings.Split(string(b), "\n")
-
-			navPrefix := idx.getEntryNavPrefix()
-			if strings.HasPrefix(lines[0], "[") {
-				// update nav
-				lines[0] = navPrefix + readTitle(entry.file)
-			} else {
-				// insert nav
-				lines = append([]string{navPrefix + readTitle(entry.file) + "\n"}, lines...)
-			}
-
-			if len(lines) > 4 {
-				if lines[len(lines)-3] == "---" {
-					lines = lines[:len(lines)-3]
-				}
-
-				if len(lines) > 4 && lines[len(lines)-5] == "---" {
-					lines = lines[:len(lines)-5]
+			// nav, TOC and backlinks go below the front matter
+			content, crlf := normalizeEOL(string(b))
+			fm, body := splitNavFrontMatter(content)
+			if genOpt.Backlinks {
+				section, err := entry.renderBacklinks(t, p.backlinks[entry.file])
+				if err != nil {
+					errs = append(errs, err)
+					continue
 				}
+				body = updateBacklinks(md, body, section)
 			}
-
-			// update bottom nav
-			bottomNav := entry.getBottomNav()
-			if bottomNav != "" {
-				lines = append(lines, bottomNav)
+			if genOpt.TOC {
+				body = updateTOC(md, body, minDepth, maxDepth)
 			}
-
-			updated := strings.Join(lines, "\n")
-			f, err := os.Create(entry.file)
-			if err == nil {
-				defer f.Close()
-				f.WriteString(updated)
+			if !genOpt.Nav {
+				errs = append(errs, p.add(entry.file, []byte(restoreEOL(fm+body, crlf))))
+				continue
 			}
-		}
-	}
-}
 
-func (e *entry) getBottomNav() string {
-	var result string
-	if e.prev != nil {
-		result += fmt.Sprintf("[« %s](%s)\n", e.prev.title, getLink(path.Base(e.prev.file)))
-	}
-	if e.next != nil {
-		if result != "" {
-			result += "\n"
-		}
-		result += fmt.Sprintf("[» %s](%s)\n", e.next.title, getLink(path.Base(e.next.file)))
-	}
-	if result != "" {
-		result = "---\n" + result
-	}
-	return result
-}
-
-type parseContentOption struct {
-	WorkDir      string
-	Content      string
-	Depth        int
-	NoHeaderLink bool
-}
-
-func parseContent(idx *index, opt *parseContentOption) string {
-	for _, subIdx := range idx.children {
-		relPath, _ := filepath.Rel(opt.WorkDir, subIdx.file)
-		if opt.Depth == 0 {
-			if opt.NoHeaderLink {
-				opt.Content += fmt.Sprintf("\n## %s\n", subIdx.title)
-			} else {
-				opt.Content += fmt.Sprintf("\n## [%s](%s)\n", subIdx.title, getLink(relPath))
+			navLine, err := entry.renderBreadcrumb(t)
+			if err != nil {
+				errs = append(errs, err)
+				continue
 			}
-		} else {
-			opt.Content += fmt.Sprintf("\n%s- [%s](%s)", strings.Repeat("  ", opt.Depth-1), subIdx.title, getLink(relPath))
-		}
-
-		opt.Content += parseContent(subIdx, &parseContentOption{
-			WorkDir:      opt.WorkDir,
-			Content:      "",
-			Depth:        opt.Depth + 1,
-			NoHeaderLink: opt.NoHeaderLink,
-		})
-	}
-
-	for _, entry := range idx.entries {
-		relPath, _ := filepath.Rel(opt.WorkDir, entry.file)
-		if opt.Depth == 0 {
-			opt.Content += fmt.Sprintf("\n[%s](%s)\n", entry.title, getLink(relPath))
-		} else {
-			opt.Content += fmt.Sprintf("\n%s- [%s](%s)", strings.Repeat("  ", opt.Depth-1), entry.title, getLink(relPath))
+			bottomNav, err := entry.renderFooter(t)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			errs = append(errs, p.add(entry.file, []byte(restoreEOL(fm+decorateNav(md, body, navLine, bottomNav), crlf))))
 		}
 	}
+	return errors.Join(errs...)
+}
 
-	if !strings.HasSuffix(opt.Content, "\n") {
-		opt.Content += "\n"
+// indexRegion returns the index region of the index file and its content
+// with LF line endings, crlf reports whether it had CRLF ones. The region
+// is not found if the file is missing.
+func (idx *Index) indexRegion() (r *region, content string, crlf bool) {
+	b, err := fs.ReadFile(idx.fsys, idx.file)
+	if err != nil {
+		return &region{start: -1, end: -1}, "", false
 	}
-
-	return opt.Content
+	content, crlf = normalizeEOL(string(b))
+	return findRegion(newMarkdown(), []byte(content), IndexStart, IndexEnd), content, crlf
 }
 
 func getLink(file string) string {
 	return strings.ReplaceAll(file, " ", "%20")
 }
 
-var dirHasMdFileMap = make(map[string]bool)
-
-func hasMdFile(dir, indexFile string) bool {
-	if v, ok := dirHasMdFileMap[dir]; ok {
-		return v
+// hasMdFile reports whether dir has a markdown file in its subtree which is
+// not excluded.
+func (c *cache) hasMdFile(dir, indexFile string) (bool, error) {
+	c.mu.Lock()
+	v, ok := c.hasMd[dir]
+	c.mu.Unlock()
+	if ok {
+		return v, nil
 	}
 
-	subExcludes := getSubExcludes(dir)
+	subExcludes := c.subRules(dir)
 
-	dirEntries, _ := os.ReadDir(dir)
+	dirEntries, err := c.readDir(dir)
+	if err != nil {
+		return false, newError("read dir", dir, err)
+	}
+	var found bool
+	var errs []error
 	for _, de := range dirEntries {
-		if matchFile(subExcludes, de.Name()) {
+		if matchRule(subExcludes, []string{de.Name()}, true) != nil {
 			continue
 		}
 		if !de.IsDir() {
-			if slices.Contains(mdExts, path.Ext(de.Name())) && de.Name() != indexFile {
-				dirHasMdFileMap[path.Join(dir, de.Name())] = true
-				dirHasMdFileMap[dir] = true
-				return true
+			if slices.Contains(mdExts, path.Ext(de.Name())) && de.Name() != indexFile &&
+				!c.readFrontMatter(path.Join(dir, de.Name())).excluded() {
+				found = true
+				break
 			}
 		} else {
-			if hasMdFile(path.Join(dir, de.Name()), indexFile) {
-				dirHasMdFileMap[dir] = true
-				return true
+			ok, err := c.hasMdFile(path.Join(dir, de.Name()), indexFile)
+			if ok {
+				found = true
+				break
 			}
+			errs = append(errs, err)
 		}
 	}
 
-	return dirHasMdFileMap[dir]
-}
-
-func matchFile(paths []string, file string) bool {
-	patterns := []gitignore.Pattern{}
-
-	for _, p := range paths {
-		patterns = append(patterns, gitignore.ParsePattern(p, nil))
+	if err := errors.Join(errs...); err != nil && !found {
+		return false, err
 	}
-	m := gitignore.NewMatcher(patterns)
-	return m.Match(strings.Split(file, "/"), true)
+	c.mu.Lock()
+	c.hasMd[dir] = found
+	c.mu.Unlock()
+	return found, nil
 }
 
-var fileTitleMap = make(map[string]string)
-
-func readTitle(file string) string {
-	if v, ok := fileTitleMap[file]; ok {
-		return v
-	}
-
-	if !slices.Contains(mdExts, path.Ext(file)) {
-		fileTitleMap[file] = path.Base(file)
-		return fileTitleMap[file]
-	}
-
-	if _, err := os.Stat(file); os.IsNotExist(err) {
-		fileTitleMap[file] = path.Base(path.Dir(file))
-		return fileTitleMap[file]
-	}
-
-	f, err := os.Open(file)
-	if err != nil {
-		fileTitleMap[file] = path.Base(file)
-		return fileTitleMap[file]
-	}
-	defer f.Close()
-
-	s := bufio.NewScanner(f)
-	for s.Scan() {
-		cut, ok := strings.CutPrefix(s.Text(), "# ")
-		if ok && len(cut) > 0 {
-			fileTitleMap[file] = strings.TrimSpace(cut)
-			return fileTitleMap[file]
-		}
-	}
-	fileTitleMap[file] = path.Base(file)
-	return fileTitleMap[file]
-}
-
-func getIgnoreEntry(ignoreFile string) []string {
+func getIgnoreEntry(fsys fs.FS, ignoreFile string) []string {
 	var result []string
-	mdiignore, err := os.Stat(ignoreFile)
+	mdiignore, err := fs.Stat(fsys, ignoreFile)
 	if err == nil && !mdiignore.IsDir() {
-		f, err := os.Open(ignoreFile)
+		f, err := fsys.Open(ignoreFile)
 		if err == nil {
 			defer f.Close()
 			s := bufio.NewScanner(f)
@@ -464,54 +573,85 @@ func getIgnoreEntry(ignoreFile string) []string {
 	return result
 }
 
-func Clean(workDir, indexFile string) {
+// Clean removes the index files, the pages generated by mdi and the nav,
+// TOC and backlinks of markdown files.
+// Files failing to be cleaned are skipped and reported in the returned error.
+func Clean(workDir, indexFile string) error {
+	return CleanFS(OSFS{}, workDir, indexFile)
+}
+
+// CleanFS is Clean on the filesystem fsys.
+func CleanFS(fsys WriteFS, workDir, indexFile string) error {
+	return clean(fsys, newMarkdown(), workDir, indexFile)
+}
+
+func clean(fsys WriteFS, md goldmark.Markdown, workDir, indexFile string) error {
 	if workDir == "" {
 		workDir = "."
 	}
-	files, err := os.ReadDir(workDir)
+	files, err := fs.ReadDir(fsys, workDir)
 	if err != nil {
-		panic(err)
+		return newError("read dir", workDir, err)
 	}
 
+	var errs []error
 	for _, f := range files {
+		file := path.Join(workDir, f.Name())
 		if f.IsDir() {
-			Clean(path.Join(workDir, f.Name()), indexFile)
-		} else {
-			if f.Name() == indexFile {
-				os.Remove(path.Join(workDir, f.Name()))
+			errs = append(errs, clean(fsys, md, file, indexFile))
+			continue
+		}
+
+		if f.Name() == indexFile {
+			errs = append(errs, cleanIndexFile(fsys, md, file))
+			continue
+		}
+
+		if slices.Contains(mdExts, path.Ext(f.Name())) {
+			b, err := fs.ReadFile(fsys, file)
+			if err != nil {
+				errs = append(errs, newError("read file", file, err))
+				continue
+			}
+			if len(b) == 0 {
+				continue
 			}
 
-			if slices.Contains(mdExts, path.Ext(f.Name())) {
-				b, err := os.ReadFile(path.Join(workDir, f.Name()))
-				if err == nil {
-					if len(b) == 0 {
-						continue
-					}
-
-					lines := strings.Split(string(b), "\n")
-
-					if len(lines) > 4 {
-						if lines[len(lines)-3] == "---" {
-							lines = lines[:len(lines)-3]
-						}
-						if lines[len(lines)-5] == "---" {
-							lines = lines[:len(lines)-5]
-						}
-					}
-					if len(lines) > 1 {
-						if strings.HasPrefix(lines[0], "[") && lines[1] == "" {
-							lines = lines[2:]
-						}
-					}
-
-					updated := strings.Join(lines, "\n")
-					updatedFile, err := os.Create(path.Join(workDir, f.Name()))
-					if err == nil {
-						defer updatedFile.Close()
-						updatedFile.WriteString(updated)
-					}
+			content, crlf := normalizeEOL(string(b))
+			fm, body := splitFrontMatter(content)
+			if parseFrontMatterBlock(fm).Generated {
+				if err := fsys.Remove(file); err != nil {
+					errs = append(errs, newError("remove file", file, err))
+				}
+				continue
+			}
+			updated := restoreEOL(fm+removeNav(md, removeTOC(md, removeBacklinks(md, body))), crlf)
+			if updated != string(b) {
+				if err := fsys.WriteFile(file, []byte(updated), 0644); err != nil {
+					errs = append(errs, newError("write file", file, err))
 				}
 			}
 		}
 	}
+	return errors.Join(errs...)
+}
+
+// cleanIndexFile removes an index file, or empties the index region of a
+// hand-written one.
+func cleanIndexFile(fsys WriteFS, md goldmark.Markdown, file string) error {
+	b, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return newError("read file", file, err)
+	}
+	content, crlf := normalizeEOL(string(b))
+	if r := findRegion(md, []byte(content), IndexStart, IndexEnd); r.found() {
+		if err := fsys.WriteFile(file, []byte(restoreEOL(r.replace(content, ""), crlf)), 0644); err != nil {
+			return newError("write file", file, err)
+		}
+		return nil
+	}
+	if err := fsys.Remove(file); err != nil {
+		return newError("remove file", file, err)
+	}
+	return nil
 }
@@ -16,7 +16,13 @@ limitations under the License.
 
 package mdi
 
-import "testing"
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
 
 func TestIncludeFile(t *testing.T) {
 	paths := []string{"**/target", "**/hello", "test-01.md", "go/**"}
@@ -37,10 +43,61 @@ func TestIncludeFile(t *testing.T) {
 	}
 
 	for _, d := range testdata {
-		actual := matchFile(paths, d.file)
+		actual := matchRule(newExcludeRules(paths, "", nil), strings.Split(d.file, "/"), true) != nil
 		if actual != d.expected {
 			t.Errorf("includeFile(%q, %q) = %v, expected %v", d.file, paths, actual, d.expected)
 		}
 	}
 	t.Log("TestIncludeFile passed")
 }
+
+func TestNewIndexError(t *testing.T) {
+	dir := t.TempDir()
+	missing := path.Join(dir, "missing")
+
+	idx, err := NewIndex(&IndexOption{WorkDir: missing})
+	var e *Error
+	if idx != nil || !errors.As(err, &e) || e.Path != missing || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("NewIndex(%q) = %v, %v, expected nil and a not exist error on the work dir", missing, idx, err)
+	}
+
+	writeTree(t, dir, map[string]string{"note.md": "# Note\n"})
+	file := path.Join(dir, "note.md")
+	idx, err = NewIndex(&IndexOption{WorkDir: file})
+	if idx != nil || !errors.Is(err, ErrNotDir) {
+		t.Errorf("NewIndex(%q) = %v, %v, expected nil and ErrNotDir", file, idx, err)
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"go/hello.md": "# Hello\n",
+		"go/vars.md":  "# Vars\n",
+	})
+	// an index file which cannot be read nor written
+	if err := os.MkdirAll(path.Join(dir, "go", defaultIndexFile, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// the index file is read for its title, the tree is still built
+	var e *Error
+	idx, err := NewIndex(&IndexOption{WorkDir: dir, RootIndexFile: path.Join(dir, "README.md"), SubIndexFile: defaultIndexFile})
+	if idx == nil || !errors.As(err, &e) || e.Path != path.Join(dir, "go", defaultIndexFile) {
+		t.Fatalf("NewIndex() = %v, expected an error on the go index file", err)
+	}
+	err = idx.Generate(&GenerationOption{Override: true, Recursive: true, Nav: true})
+
+	e = nil
+	if !errors.As(err, &e) || e.Path != path.Join(dir, "go", defaultIndexFile) {
+		t.Fatalf("Generate() = %v, expected an error on the go index file", err)
+	}
+	// other files are still generated
+	b, err := os.ReadFile(path.Join(dir, "go/hello.md"))
+	if err != nil || string(b) == "# Hello\n" {
+		t.Errorf("go/hello.md = %q, %v, expected nav to be generated", b, err)
+	}
+	if _, err := os.Stat(path.Join(dir, "README.md")); err != nil {
+		t.Errorf("README.md not generated: %v", err)
+	}
+}
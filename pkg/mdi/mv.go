@@ -0,0 +1,302 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// ErrMoveIntoSelf is returned when a directory is moved into itself.
+var ErrMoveIntoSelf = errors.New("cannot move a directory into itself")
+
+// linkDest matches the destination of inline links and images, and of
+// link reference definitions.
+var linkDest = regexp.MustCompile(`\]\(\s*(<[^>\n]*>|[^)\s]+)|(?m)^ {0,3}\[[^\]\n]+\]:[ \t]*(<[^>\n]*>|\S+)`)
+
+// Move moves the file or directory oldPath to newPath, into newPath if it
+// is an existing directory. The relative links to the moved files in the
+// index files, entries and draft or `mdi_ignore` notes of the tree, and the
+// relative links of the moved markdown files, are rewritten. Files excluded
+// by ignore patterns are left untouched. The index is regenerated with genOpt
+// afterwards. Files failing to be rewritten are reported in the returned
+// error. Only trees on the host filesystem can be moved in.
+func Move(idxOpt *IndexOption, genOpt *GenerationOption, oldPath, newPath string) error {
+	if _, ok := idxOpt.filesystem().(OSFS); !ok {
+		return newError("move", oldPath, errors.ErrUnsupported)
+	}
+	oldPath, newPath = path.Clean(filepath.ToSlash(oldPath)), path.Clean(filepath.ToSlash(newPath))
+	if _, err := os.Stat(oldPath); err != nil {
+		return newError("stat", oldPath, err)
+	}
+	if fi, err := os.Stat(newPath); err == nil {
+		if !fi.IsDir() {
+			return newError("move", newPath, fs.ErrExist)
+		}
+		newPath = path.Join(newPath, path.Base(oldPath))
+		if _, err := os.Stat(newPath); err == nil {
+			return newError("move", newPath, fs.ErrExist)
+		}
+	}
+	oldAbs, newAbs := absPath(oldPath), absPath(newPath)
+	if isWithin(newAbs, oldAbs) {
+		return newError("move", oldPath, ErrMoveIntoSelf)
+	}
+	// moved returns the path of file after the move
+	moved := func(file string) string {
+		if abs := absPath(file); isWithin(abs, oldAbs) {
+			return path.Join(newPath, strings.TrimPrefix(abs, oldAbs))
+		}
+		return file
+	}
+
+	// markdown files of the tree, those excluded by front matter, and the
+	// moved ones
+	opt := *idxOpt
+	idx, err := NewIndex(&opt)
+	if idx == nil {
+		return err
+	}
+	var files []string
+	idx.Walk(func(i *Index) error {
+		if idx.exists(i.file) {
+			files = append(files, i.file)
+		}
+		for _, e := range i.entries {
+			files = append(files, e.file)
+		}
+		for _, s := range i.skipped {
+			switch s.Reason {
+			case SkipFrontMatter:
+				files = append(files, path.Join(idx.workDir, s.Path))
+			case SkipNoMarkdown:
+				files = append(files, idx.cache.excludedNotes(path.Join(idx.workDir, s.Path))...)
+			}
+		}
+		return nil
+	})
+	filepath.WalkDir(oldPath, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && isMarkdown(p) {
+			files = append(files, filepath.ToSlash(p))
+		}
+		return nil
+	})
+
+	type rewrite struct {
+		file    string
+		content string
+	}
+	var rewrites []rewrite
+	var errs []error
+	seen := make(map[string]bool)
+	md := newMarkdown()
+	for _, file := range files {
+		abs := absPath(file)
+		if seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		b, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, newError("read file", file, err))
+			continue
+		}
+		from, to := path.Dir(abs), path.Dir(absPath(moved(file)))
+		content := rewriteLinks(md, string(b), func(dest string) string {
+			return moveLink(dest, from, to, func(target string) string {
+				return absPath(moved(target))
+			})
+		})
+		if content != string(b) {
+			rewrites = append(rewrites, rewrite{file: moved(file), content: content})
+		}
+	}
+
+	if err := os.MkdirAll(path.Dir(newPath), 0755); err != nil {
+		return newError("move", oldPath, err)
+	}
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return newError("move", oldPath, err)
+	}
+	if genOpt.Verbose {
+		fmt.Printf("OK: moved %s to %s\n", oldPath, newPath)
+	}
+	for _, r := range rewrites {
+		if err := os.WriteFile(r.file, []byte(r.content), 0644); err != nil {
+			errs = append(errs, newError("write file", r.file, err))
+		} else if genOpt.Verbose {
+			fmt.Printf("OK: rewrote links of file: %s\n", r.file)
+		}
+	}
+
+	opt = *idxOpt
+	idx, err = NewIndex(&opt)
+	errs = append(errs, err)
+	if idx != nil {
+		errs = append(errs, idx.Generate(genOpt))
+	}
+	return errors.Join(errs...)
+}
+
+// excludedNotes returns the markdown files excluded by their front matter
+// in the subtree of dir, a directory without entries. The files excluded by
+// ignore patterns are left out.
+func (c *cache) excludedNotes(dir string) []string {
+	dirEntries, err := c.readDir(dir)
+	if err != nil {
+		return nil
+	}
+	subExcludes := c.subRules(dir)
+	var files []string
+	for _, de := range dirEntries {
+		file := path.Join(dir, de.Name())
+		switch {
+		case matchRule(subExcludes, []string{de.Name()}, de.IsDir()) != nil:
+		case de.IsDir():
+			files = append(files, c.excludedNotes(file)...)
+		case isMarkdown(file) && c.readFrontMatter(file).excluded():
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// rewriteLinks replaces the destinations of the links of content with the
+// result of fn, links in code are left untouched.
+func rewriteLinks(md goldmark.Markdown, content string, fn func(dest string) string) string {
+	fm, body := splitFrontMatter(content)
+	code := codeRanges(md, []byte(body))
+	inCode := func(i int) bool {
+		for _, r := range code {
+			if i >= r[0] && i < r[1] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range linkDest.FindAllStringSubmatchIndex(body, -1) {
+		start, end := m[2], m[3]
+		if start < 0 {
+			start, end = m[4], m[5]
+		}
+		if inCode(start) {
+			continue
+		}
+		raw := body[start:end]
+		angled := strings.HasPrefix(raw, "<")
+		dest := strings.TrimSuffix(strings.TrimPrefix(raw, "<"), ">")
+		replaced := fn(dest)
+		if replaced == dest {
+			continue
+		}
+		if angled {
+			replaced = "<" + replaced + ">"
+		} else {
+			replaced = getLink(replaced)
+		}
+		sb.WriteString(body[last:start])
+		sb.WriteString(replaced)
+		last = end
+	}
+	if last == 0 {
+		return content
+	}
+	sb.WriteString(body[last:])
+	return fm + sb.String()
+}
+
+// codeRanges returns the byte ranges of the code blocks and code spans of src.
+func codeRanges(md goldmark.Markdown, src []byte) [][2]int {
+	var result [][2]int
+	ast.Walk(md.Parser().Parse(text.NewReader(src)), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock:
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				result = append(result, [2]int{lines.At(i).Start, lines.At(i).Stop})
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeSpan:
+			for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+				if t, ok := c.(*ast.Text); ok {
+					result = append(result, [2]int{t.Segment.Start, t.Segment.Stop})
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return result
+}
+
+// moveLink returns dest of a file moved from the dir from to the dir to,
+// pointing at the moved target of its target.
+func moveLink(dest, from, to string, moved func(string) string) string {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || path.IsAbs(u.Path) {
+		return dest
+	}
+	target := path.Join(from, u.Path)
+	newTarget := moved(target)
+	if from == to && newTarget == target {
+		return dest
+	}
+
+	rel, err := filepath.Rel(to, newTarget)
+	if err != nil {
+		return dest
+	}
+	rel = filepath.ToSlash(rel)
+	if strings.HasSuffix(u.Path, "/") {
+		rel += "/"
+	}
+	if i := strings.IndexAny(dest, "?#"); i >= 0 {
+		rel += dest[i:]
+	}
+	return rel
+}
+
+func absPath(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return file
+	}
+	return filepath.ToSlash(abs)
+}
+
+// isWithin reports whether file is dir or is below dir.
+func isWithin(file, dir string) bool {
+	rel, err := filepath.Rel(dir, file)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestMove(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"root.md": "---\ntitle: Root\n---\n# Root\n\n" +
+			"[basics](go/basics.md) [anchor](go/basics.md#setup) [vars](go/vars.md)\n" +
+			"![img](<go/img/logo one.png>)\n\n" +
+			"```\n[in code](go/basics.md)\n```\n\n" +
+			"[ref]: ./go/basics.md\n",
+		"go/basics.md":        "# Basics\n\n[vars](vars.md) [root](../root.md) [self](#basics) [ext](https://example.com/vars.md)\n",
+		"go/vars.md":          "# Vars\n\n[basics](basics.md)\n",
+		"go/img/logo one.png": "PNG",
+		"my notes/a.md":       "# A\n\n[basics](../go/basics.md)\n",
+		"go/hidden.md":        "---\nmdi_ignore: true\n---\n# Hidden\n\n[basics](basics.md)\n",
+		"notes/d.md":          "---\ndraft: true\n---\n# D\n\n[basics](../go/basics.md)\n",
+	})
+	idxOpt := &IndexOption{WorkDir: dir, IndexTitle: "Notes", RootIndexFile: path.Join(dir, "README.md"), SubIndexFile: defaultIndexFile}
+	genOpt := &GenerationOption{Override: true, Recursive: true}
+	read := func(file string) string {
+		t.Helper()
+		b, err := os.ReadFile(path.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	// move a file to a new dir
+	if err := Move(idxOpt, genOpt, path.Join(dir, "go/basics.md"), path.Join(dir, "my notes/golang/basics.md")); err != nil {
+		t.Fatal(err)
+	}
+	testdata := []struct {
+		file     string
+		expected string
+	}{
+		{"root.md", "---\ntitle: Root\n---\n# Root\n\n" +
+			"[basics](my%20notes/golang/basics.md) [anchor](my%20notes/golang/basics.md#setup) [vars](go/vars.md)\n" +
+			"![img](<go/img/logo one.png>)\n\n" +
+			"```\n[in code](go/basics.md)\n```\n\n" +
+			"[ref]: my%20notes/golang/basics.md\n"},
+		{"my notes/golang/basics.md", "# Basics\n\n[vars](../../go/vars.md) [root](../../root.md) [self](#basics) [ext](https://example.com/vars.md)\n"},
+		{"go/vars.md", "# Vars\n\n[basics](../my%20notes/golang/basics.md)\n"},
+		{"my notes/a.md", "# A\n\n[basics](golang/basics.md)\n"},
+		{"go/hidden.md", "---\nmdi_ignore: true\n---\n# Hidden\n\n[basics](../my%20notes/golang/basics.md)\n"},
+		{"notes/d.md", "---\ndraft: true\n---\n# D\n\n[basics](../my%20notes/golang/basics.md)\n"},
+	}
+	for _, d := range testdata {
+		if actual := read(d.file); actual != d.expected {
+			t.Errorf("Move() file %s = %q, expected %q", d.file, actual, d.expected)
+		}
+	}
+	if index := read("my notes/" + defaultIndexFile); !strings.Contains(index, "[Basics](golang/basics.md)") {
+		t.Errorf("Move() did not regenerate the index: %s", index)
+	}
+
+	// move a dir into an existing dir
+	if err := Move(idxOpt, genOpt, path.Join(dir, "go"), path.Join(dir, "my notes")); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := read("root.md"), "![img](<my notes/go/img/logo one.png>)"; !strings.Contains(actual, expected) {
+		t.Errorf("Move() file root.md = %q, expected %q", actual, expected)
+	}
+	if actual, expected := read("my notes/go/vars.md"), "[basics](../golang/basics.md)"; !strings.Contains(actual, expected) {
+		t.Errorf("Move() file vars.md = %q, expected %q", actual, expected)
+	}
+	if actual, expected := read("my notes/golang/basics.md"), "[vars](../go/vars.md)"; !strings.Contains(actual, expected) {
+		t.Errorf("Move() file basics.md = %q, expected %q", actual, expected)
+	}
+
+	var e *Error
+	if err := Move(idxOpt, genOpt, path.Join(dir, "my notes"), path.Join(dir, "my notes/go/sub")); !errors.Is(err, ErrMoveIntoSelf) {
+		t.Errorf("Move(into self) = %v, expected ErrMoveIntoSelf", err)
+	}
+	if err := Move(idxOpt, genOpt, path.Join(dir, "root.md"), path.Join(dir, "my notes/a.md")); !errors.As(err, &e) || !errors.Is(err, os.ErrExist) {
+		t.Errorf("Move(onto a file) = %v, expected ErrExist", err)
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"errors"
+	"io/fs"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+)
+
+// The nav written by older versions, without markers: a breadcrumb line
+// of links to index files, and a footer of prev/next links to neighbor
+// entries below a `---` line.
+var (
+	legacyBreadcrumb = regexp.MustCompile(`^(\[.*?\]\((\.\./)*[^/\s()]+\.md\) / )+\S.*$`)
+	legacyPrev       = regexp.MustCompile(`^\[« .*\]\([^/\s()]+\.md\)$`)
+	legacyNext       = regexp.MustCompile(`^\[» .*\]\([^/\s()]+\.md\)$`)
+)
+
+// decorateNav puts the breadcrumb on top of body and the footer at its
+// bottom, each between its markers. The nav of older versions, without
+// markers, is replaced.
+func decorateNav(md goldmark.Markdown, body, breadcrumb, footer string) string {
+	top := findRegion(md, []byte(body), NavStart, NavEnd)
+	if !top.found() && !findRegion(md, []byte(body), FooterStart, FooterEnd).found() {
+		body = removeLegacyNav(body)
+	}
+
+	if top = findRegion(md, []byte(body), NavStart, NavEnd); top.found() {
+		body = top.replace(body, breadcrumb)
+	} else {
+		body = top.wrap(breadcrumb) + "\n" + body
+	}
+
+	bottom := findRegion(md, []byte(body), FooterStart, FooterEnd)
+	switch {
+	case bottom.found() && footer != "":
+		body = bottom.replace(body, footer)
+	case bottom.found():
+		body = bottom.remove(body)
+	case footer != "":
+		if !strings.HasSuffix(body, "\n") {
+			body += "\n"
+		}
+		body += "\n" + bottom.wrap(footer)
+	}
+	return body
+}
+
+// removeNav removes the breadcrumb and the footer from body.
+func removeNav(md goldmark.Markdown, body string) string {
+	top := findRegion(md, []byte(body), NavStart, NavEnd)
+	bottom := findRegion(md, []byte(body), FooterStart, FooterEnd)
+	if !top.found() && !bottom.found() {
+		return removeLegacyNav(body)
+	}
+	// the footer first, the offsets of the breadcrumb are kept
+	if bottom.found() {
+		body = bottom.remove(body)
+	}
+	if top.found() {
+		body = top.remove(body)
+	}
+	return body
+}
+
+// legacyNav finds the nav of older versions in the lines of a body: the
+// breadcrumb is lines[:top] and the footer lines[bottom:]. A body without
+// breadcrumb has no legacy nav, top is 0 and bottom len(lines).
+func legacyNav(lines []string) (top, bottom int) {
+	bottom = len(lines)
+	if len(lines) < 2 || !legacyBreadcrumb.MatchString(lines[0]) || lines[1] != "" {
+		return 0, bottom
+	}
+	top = 2
+
+	// the trailing newlines of the footer may have been edited
+	i := len(lines) - 1
+	for i >= top && strings.TrimSpace(lines[i]) == "" {
+		i--
+	}
+	switch {
+	case i-3 >= top && legacyNext.MatchString(lines[i]) && lines[i-1] == "" && legacyPrev.MatchString(lines[i-2]) && lines[i-3] == "---":
+		bottom = i - 3
+	case i-1 >= top && (legacyPrev.MatchString(lines[i]) || legacyNext.MatchString(lines[i])) && lines[i-1] == "---":
+		bottom = i - 1
+	}
+	return top, bottom
+}
+
+// removeLegacyNav removes the nav of older versions from body.
+func removeLegacyNav(body string) string {
+	lines := strings.Split(body, "\n")
+	top, bottom := legacyNav(lines)
+	return strings.Join(lines[top:bottom], "\n")
+}
+
+// splitNavFrontMatter is splitFrontMatter for the files decorated by older
+// versions with the breadcrumb above the front matter: the breadcrumb is
+// moved to the top of body.
+func splitNavFrontMatter(content string) (string, string) {
+	if fm, body := splitFrontMatter(content); fm != "" {
+		return fm, body
+	}
+	lines := strings.Split(content, "\n")
+	top, _ := legacyNav(lines)
+	if top == 0 {
+		return "", content
+	}
+	fm, body := splitFrontMatter(strings.Join(lines[top:], "\n"))
+	if fm == "" {
+		return "", content
+	}
+	return fm, strings.Join(lines[:top], "\n") + "\n" + body
+}
+
+// migrateNav wraps the nav of older versions in body in markers, it
+// reports whether body has such a nav.
+func migrateNav(md goldmark.Markdown, body string) (string, bool) {
+	lines := strings.Split(body, "\n")
+	top, bottom := legacyNav(lines)
+	if top == 0 {
+		return body, false
+	}
+	footer := strings.TrimRight(strings.Join(lines[bottom:], "\n"), "\n")
+	return decorateNav(md, strings.Join(lines[top:bottom], "\n"), lines[0], footer), true
+}
+
+// PlanMigrate collects the entries of the tree decorated by older versions,
+// with their nav wrapped in markers and otherwise unchanged.
+func (idx *Index) PlanMigrate() (*Plan, error) {
+	p := &Plan{fsys: idx.fsys}
+	md := newMarkdown()
+	var errs []error
+	idx.Walk(func(idx *Index) error {
+		for _, entry := range idx.entries {
+			b, err := fs.ReadFile(idx.fsys, entry.file)
+			if err != nil {
+				errs = append(errs, newError("read file", entry.file, err))
+				continue
+			}
+			content, crlf := normalizeEOL(string(b))
+			fm, body := splitNavFrontMatter(content)
+			if migrated, ok := migrateNav(md, body); ok {
+				errs = append(errs, p.add(entry.file, []byte(restoreEOL(fm+migrated, crlf))))
+			}
+		}
+		return nil
+	})
+	return p, errors.Join(errs...)
+}
+
+// Migrate wraps the nav of the entries decorated by older versions in
+// markers, so that later generations find it.
+func (idx *Index) Migrate(genOpt *GenerationOption) error {
+	p, err := idx.PlanMigrate()
+	return errors.Join(err, p.Apply(genOpt))
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestDecorateNav(t *testing.T) {
+	nav := NavStart + "\n[Home](README.md) / A\n" + NavEnd + "\n"
+	footer := FooterStart + "\n---\n[» B](b.md)\n" + FooterEnd + "\n"
+	testdata := []struct {
+		body     string
+		expected string
+	}{
+		{"# A\n\ntext\n", nav + "\n# A\n\ntext\n\n" + footer},
+		{"# A\n\ntext", nav + "\n# A\n\ntext\n\n" + footer},
+		// nav of older versions
+		{"[Home](README.md) / Old\n\n# A\n\ntext\n\n---\n[» Old](old.md)\n", nav + "\n# A\n\ntext\n\n" + footer},
+		// a link first and a rule last are content
+		{"[link](x.md) is a link\n\ntext\n\n---\n[» x](x.md)\n", nav + "\n[link](x.md) is a link\n\ntext\n\n---\n[» x](x.md)\n\n" + footer},
+		{"[Home](README.md)\n\ntext\n---\nend\n", nav + "\n[Home](README.md)\n\ntext\n---\nend\n\n" + footer},
+		{NavStart + "\n" + NavEnd + "\n[Home](README.md) / A\n\ntext\n\n---\n[» x](x.md)\n",
+			nav + "[Home](README.md) / A\n\ntext\n\n---\n[» x](x.md)\n\n" + footer},
+		{nav + "\n# A\n\ntext\n\n" + FooterStart + "\nold\n" + FooterEnd + "\n", nav + "\n# A\n\ntext\n\n" + footer},
+	}
+	for _, d := range testdata {
+		actual := decorateNav(newMarkdown(), d.body, "[Home](README.md) / A", "---\n[» B](b.md)\n")
+		if actual != d.expected {
+			t.Errorf("decorateNav(%q) = %q, expected %q", d.body, actual, d.expected)
+		}
+	}
+
+	// the footer is removed without neighbors
+	body := nav + "\n# A\n\ntext\n\n" + footer
+	if actual, expected := decorateNav(newMarkdown(), body, "[Home](README.md) / A", ""), nav+"\n# A\n\ntext\n"; actual != expected {
+		t.Errorf("decorateNav(%q) = %q, expected %q", body, actual, expected)
+	}
+	if actual, expected := removeNav(newMarkdown(), body), "# A\n\ntext\n"; actual != expected {
+		t.Errorf("removeNav(%q) = %q, expected %q", body, actual, expected)
+	}
+}
+
+func TestLegacyNav(t *testing.T) {
+	testdata := []struct {
+		body        string
+		top, bottom int
+	}{
+		{"[Home](../README.md) / [go](zz_generated_mdi.md) / A\n\ntext\n\n---\n[« P](p.md)\n\n[» N](n.md)\n", 2, 4},
+		{"[Home](README.md) / A\n\ntext\n---\n[» N](n%20b.md)\n\n\n", 2, 3},
+		{"[Home](README.md) / A\n\ntext\n", 2, 4},
+		// no breadcrumb, no legacy nav
+		{"text\n\n---\n[» N](n.md)\n", 0, 5},
+		{"[Home](README.md) / A\ntext\n", 0, 3},
+		{"[Home](https://example.com) / A\n\ntext\n", 0, 4},
+		// not a footer
+		{"[Home](README.md) / A\n\ntext\n\n---\n[» N](../n.md)\n", 2, 7},
+		{"[Home](README.md) / A\n\ntext\n\n---\nend\n", 2, 7},
+	}
+	for _, d := range testdata {
+		top, bottom := legacyNav(strings.Split(d.body, "\n"))
+		if top != d.top || bottom != d.bottom {
+			t.Errorf("legacyNav(%q) = %d, %d, expected %d, %d", d.body, top, bottom, d.top, d.bottom)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	idx := newTestIndex(t, map[string]string{
+		"go/a.md": "[Notes](../README.md) / [go](zz_generated_mdi.md) / A\n\n# A\n\n---\n[» B](b.md)\n",
+		"go/b.md": "---\r\ntitle: B\r\n---\r\n[Notes](../README.md) / [go](zz_generated_mdi.md) / B\r\n\r\n# B\r\n\r\n---\r\n[« A](a.md)\r\n",
+		"go/c.md": "[link](x.md)\n\n# C\n",
+		"go/d.md": "[Notes](../README.md) / [go](zz_generated_mdi.md) / D\n\n---\ntitle: Real Title\n---\n# D\n",
+	})
+	for _, e := range idx.Children()[0].Entries() {
+		if e.file == path.Join(idx.workDir, "go/d.md") && e.Title() != "Real Title" {
+			t.Errorf("Title() = %q, expected the title of the front matter below a legacy nav", e.Title())
+		}
+	}
+	if err := idx.Migrate(&GenerationOption{}); err != nil {
+		t.Fatal(err)
+	}
+
+	testdata := []struct {
+		file     string
+		expected string
+	}{
+		{"go/a.md", NavStart + "\n[Notes](../README.md) / [go](zz_generated_mdi.md) / A\n" + NavEnd + "\n\n# A\n\n" + FooterStart + "\n---\n[» B](b.md)\n" + FooterEnd + "\n"},
+		{"go/b.md", "---\r\ntitle: B\r\n---\r\n" + NavStart + "\r\n[Notes](../README.md) / [go](zz_generated_mdi.md) / B\r\n" + NavEnd + "\r\n\r\n# B\r\n\r\n" + FooterStart + "\r\n---\r\n[« A](a.md)\r\n" + FooterEnd + "\r\n"},
+		{"go/c.md", "[link](x.md)\n\n# C\n"},
+		{"go/d.md", "---\ntitle: Real Title\n---\n" + NavStart + "\n[Notes](../README.md) / [go](zz_generated_mdi.md) / D\n" + NavEnd + "\n\n# D\n"},
+	}
+	for _, d := range testdata {
+		b, _ := os.ReadFile(path.Join(idx.workDir, d.file))
+		if string(b) != d.expected {
+			t.Errorf("Migrate() wrote %q to %s, expected %q", b, d.file, d.expected)
+		}
+	}
+
+	// migrated files are stable
+	p, err := idx.PlanMigrate()
+	if err != nil || len(p.Changes) != 0 {
+		t.Errorf("PlanMigrate() = %d changes, %v, expected none after Migrate()", len(p.Changes), err)
+	}
+}
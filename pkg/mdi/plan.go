@@ -0,0 +1,208 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+type ChangeKind int
+
+const (
+	Unchanged ChangeKind = iota
+	Created
+	Modified
+	Deleted
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Created:
+		return "created"
+	case Modified:
+		return "modified"
+	case Deleted:
+		return "deleted"
+	default:
+		return "unchanged"
+	}
+}
+
+// Change is a planned write of an index file or a nav-decorated entry, or
+// the removal of a stale generated page.
+type Change struct {
+	File   string
+	Before []byte
+	After  []byte
+	Kind   ChangeKind
+}
+
+// Plan holds the changes of one generation, in the order they are written.
+type Plan struct {
+	Changes []*Change
+	// filter restricts the plan to the files it accepts, all files if nil.
+	filter func(file string) bool
+	// fsys is the filesystem the plan is read from and applied to, OSFS if
+	// nil.
+	fsys fs.FS
+	// backlinks are the entries linking to each entry of the tree.
+	backlinks map[string][]*Entry
+	// planned are the files of Changes.
+	planned map[string]bool
+}
+
+func (p *Plan) filesystem() fs.FS {
+	if p.fsys == nil {
+		return OSFS{}
+	}
+	return p.fsys
+}
+
+func (p *Plan) wants(file string) bool {
+	return p.filter == nil || p.filter(file)
+}
+
+func (p *Plan) add(file string, content []byte) error {
+	c := &Change{File: file, After: content, Kind: Created}
+	b, err := fs.ReadFile(p.filesystem(), file)
+	if err == nil {
+		c.Before = b
+		c.Kind = Modified
+		if bytes.Equal(b, content) {
+			c.Kind = Unchanged
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return newError("read file", file, err)
+	}
+	return p.append(c)
+}
+
+// remove adds the removal of file.
+func (p *Plan) remove(file string) error {
+	b, err := fs.ReadFile(p.filesystem(), file)
+	if err != nil {
+		return newError("read file", file, err)
+	}
+	return p.append(&Change{File: file, Before: b, Kind: Deleted})
+}
+
+// append adds c, a file is changed at most once by a plan.
+func (p *Plan) append(c *Change) error {
+	if p.planned[c.File] {
+		return newError("plan", c.File, errors.New("file already planned"))
+	}
+	if p.planned == nil {
+		p.planned = make(map[string]bool)
+	}
+	p.planned[c.File] = true
+	p.Changes = append(p.Changes, c)
+	return nil
+}
+
+// Apply writes every created or modified file of the plan and removes the
+// deleted ones, files failing to be written are reported in the returned
+// error.
+func (p *Plan) Apply(genOpt *GenerationOption) error {
+	fsys, _ := p.filesystem().(WriteFS)
+	return p.ApplyTo(fsys, genOpt)
+}
+
+// ApplyTo writes every created or modified file of the plan to fsys, at the
+// same name, instead of the filesystem it was planned on, and removes the
+// deleted ones from fsys if they exist. Writes to a nil fsys fail with
+// ErrReadOnly.
+func (p *Plan) ApplyTo(fsys WriteFS, genOpt *GenerationOption) error {
+	var errs []error
+	for _, c := range p.Changes {
+		if c.Kind == Unchanged {
+			continue
+		}
+		if c.Kind == Deleted {
+			err := ErrReadOnly
+			if fsys != nil {
+				err = fsys.Remove(c.File)
+			}
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, newError("remove file", c.File, err))
+			} else if genOpt.Verbose {
+				fmt.Printf("OK: removed file: %s\n", c.File)
+			}
+			continue
+		}
+		err := ErrReadOnly
+		if fsys != nil {
+			err = fsys.WriteFile(c.File, c.After, 0644)
+		}
+		if err != nil {
+			errs = append(errs, newError("write file", c.File, err))
+		} else {
+			if genOpt.Verbose {
+				fmt.Printf("OK: generated file: %s\n", c.File)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Count returns the number of changes of the given kind.
+func (p *Plan) Count(kind ChangeKind) int {
+	var n int
+	for _, c := range p.Changes {
+		if c.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteDiff renders the plan as a unified diff followed by a summary.
+func (p *Plan) WriteDiff(w io.Writer) error {
+	for _, c := range p.Changes {
+		if c.Kind == Unchanged {
+			continue
+		}
+		if _, err := io.WriteString(w, unifiedDiff(c.File, c.Before, c.After, c.Kind)); err != nil {
+			return err
+		}
+	}
+	return p.WriteSummary(w)
+}
+
+// WriteSummary lists the files of the plan grouped by change kind, the
+// deleted files are counted only if there are any.
+func (p *Plan) WriteSummary(w io.Writer) error {
+	for _, kind := range []ChangeKind{Created, Modified, Deleted} {
+		for _, c := range p.Changes {
+			if c.Kind == kind {
+				if _, err := fmt.Fprintf(w, "%s: %s\n", kind, c.File); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	var deleted string
+	if n := p.Count(Deleted); n > 0 {
+		deleted = fmt.Sprintf(", %d deleted", n)
+	}
+	_, err := fmt.Fprintf(w, "%d created, %d modified%s, %d unchanged\n",
+		p.Count(Created), p.Count(Modified), deleted, p.Count(Unchanged))
+	return err
+}
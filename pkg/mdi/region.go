@@ -0,0 +1,147 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Markers of the generated regions of markdown files. The lines between
+// the markers are replaced on every generation, the rest of the file is
+// kept.
+const (
+	// IndexStart and IndexEnd mark the index in a hand-written index file.
+	IndexStart = "<!-- mdi:index:start -->"
+	IndexEnd   = "<!-- mdi:index:end -->"
+	// NavStart and NavEnd mark the breadcrumb of entries.
+	NavStart = "<!-- mdi:nav:start -->"
+	NavEnd   = "<!-- mdi:nav:end -->"
+	// FooterStart and FooterEnd mark the prev/next nav of entries.
+	FooterStart = "<!-- mdi:footer:start -->"
+	FooterEnd   = "<!-- mdi:footer:end -->"
+	// TOCStart and TOCEnd mark the table of contents of entries.
+	TOCStart = "<!-- mdi:toc -->"
+	TOCEnd   = "<!-- /mdi:toc -->"
+	// BacklinksStart and BacklinksEnd mark the backlinks section of entries.
+	BacklinksStart = "<!-- mdi:backlinks:start -->"
+	BacklinksEnd   = "<!-- mdi:backlinks:end -->"
+)
+
+// region is a generated region of a markdown file, between the line of
+// its start marker and the line of its end marker.
+type region struct {
+	startMarker, endMarker string
+	// start and end are the offsets of the start marker line and after the
+	// end marker line, -1 if the markers are missing.
+	start, end int
+}
+
+// findRegion finds the first region between the markers in src, markers
+// in code are ignored.
+func findRegion(md goldmark.Markdown, src []byte, startMarker, endMarker string) *region {
+	r := &region{startMarker: startMarker, endMarker: endMarker, start: -1, end: -1}
+	ast.Walk(md.Parser().Parse(text.NewReader(src)), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || n.Type() != ast.TypeBlock {
+			return ast.WalkContinue, nil
+		}
+		if _, ok := n.(*ast.HTMLBlock); !ok || n.Lines().Len() == 0 {
+			return ast.WalkContinue, nil
+		}
+		line := n.Lines().At(0)
+		switch strings.TrimSpace(string(line.Value(src))) {
+		case startMarker:
+			if r.start < 0 {
+				r.start = lineStart(src, line.Start)
+			}
+		case endMarker:
+			if r.start >= 0 {
+				r.end = lineEnd(src, line.Start)
+				return ast.WalkStop, nil
+			}
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	if r.end < 0 {
+		r.start = -1
+	}
+	return r
+}
+
+func (r *region) found() bool {
+	return r.start >= 0
+}
+
+// wrap returns content between the markers of r.
+func (r *region) wrap(content string) string {
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return r.startMarker + "\n" + content + r.endMarker + "\n"
+}
+
+// replace replaces the region found in s with content between the markers.
+func (r *region) replace(s, content string) string {
+	return s[:r.start] + r.wrap(content) + s[r.end:]
+}
+
+// remove removes the region found in s with its markers, and the blank
+// line separating it from the rest of s.
+func (r *region) remove(s string) string {
+	before, after := s[:r.start], s[r.end:]
+	switch {
+	case (before == "" || strings.HasSuffix(before, "\n\n")) && strings.HasPrefix(after, "\n"):
+		after = after[1:]
+	case after == "" && strings.HasSuffix(before, "\n\n"):
+		before = before[:len(before)-1]
+	}
+	return before + after
+}
+
+// lineStart returns the offset of the line of offset i.
+func lineStart(src []byte, i int) int {
+	return bytes.LastIndexByte(src[:i], '\n') + 1
+}
+
+// lineEnd returns the offset after the newline of the line of offset i.
+func lineEnd(src []byte, i int) int {
+	if j := bytes.IndexByte(src[i:], '\n'); j >= 0 {
+		return i + j + 1
+	}
+	return len(src)
+}
+
+// normalizeEOL converts the CRLF line endings of s to LF, it reports
+// whether s has any, to restore them after editing s.
+func normalizeEOL(s string) (string, bool) {
+	if !strings.Contains(s, "\r\n") {
+		return s, false
+	}
+	return strings.ReplaceAll(s, "\r\n", "\n"), true
+}
+
+// restoreEOL converts the LF line endings of s to CRLF if crlf is true.
+func restoreEOL(s string, crlf bool) string {
+	if !crlf {
+		return s
+	}
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestFindRegion(t *testing.T) {
+	testdata := []struct {
+		src        string
+		start, end int
+	}{
+		{"a\n" + IndexStart + "\nb\n" + IndexEnd + "\nc\n", 2, 2 + len(IndexStart) + len(IndexEnd) + 4},
+		{IndexStart + "\n" + IndexEnd, 0, len(IndexStart) + len(IndexEnd) + 1},
+		// missing end
+		{IndexStart + "\nb\n", -1, -1},
+		// in code
+		{"```\n" + IndexStart + "\n" + IndexEnd + "\n```\n", -1, -1},
+		{"`" + IndexStart + "`\n\n" + IndexEnd + "\n", -1, -1},
+	}
+	for _, d := range testdata {
+		r := findRegion(newMarkdown(), []byte(d.src), IndexStart, IndexEnd)
+		if r.start != d.start || r.end != d.end {
+			t.Errorf("findRegion(%q) = %d, %d, expected %d, %d", d.src, r.start, r.end, d.start, d.end)
+		}
+	}
+}
+
+func TestIndexRegion(t *testing.T) {
+	readme := "# Project\n\nIntro.\n\n" + IndexStart + "\nold\n" + IndexEnd + "\n\n## Contributing\n"
+	idx := newTestIndex(t, map[string]string{
+		"README.md":   readme,
+		"go/hello.md": "# Hello\n",
+		"root.md":     "# Root\n",
+	})
+
+	// generated without override
+	if err := idx.Generate(&GenerationOption{}); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(idx.file)
+	expected := "# Project\n\nIntro.\n\n" + IndexStart + "\n\n## [go](go/zz_generated_mdi.md)\n\n- [Hello](go/hello.md)\n\n[Root](root.md)\n" + IndexEnd + "\n\n## Contributing\n"
+	if string(b) != expected {
+		t.Errorf("Generate() wrote %q, expected %q", b, expected)
+	}
+
+	if err := Clean(idx.workDir, path.Base(idx.file)); err != nil {
+		t.Fatal(err)
+	}
+	b, _ = os.ReadFile(idx.file)
+	if expected := "# Project\n\nIntro.\n\n" + IndexStart + "\n" + IndexEnd + "\n\n## Contributing\n"; string(b) != expected {
+		t.Errorf("Clean() wrote %q, expected %q", b, expected)
+	}
+}
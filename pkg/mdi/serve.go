@@ -0,0 +1,214 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reloadPath is the server-sent events endpoint of the live reload.
+const reloadPath = "/_mdi/reload"
+
+const reloadScript = `<script>new EventSource("` + reloadPath + `").onmessage = () => location.reload();</script>`
+
+// Server serves the html site of an index tree from memory, the same site
+// as Build renders, with the breadcrumb and prev/next nav rendered if
+// GenerationOption.Nav is set. Open pages reload when the site is rebuilt.
+type Server struct {
+	idxOpt IndexOption
+	genOpt *GenerationOption
+
+	mu    sync.RWMutex
+	files map[string][]byte
+	// clients are the channels of the open pages waiting for a reload.
+	clients map[chan struct{}]bool
+}
+
+// NewServer returns a server of the site of the work dir, built once. The
+// server is returned even if the build fails.
+func NewServer(idxOpt *IndexOption, genOpt *GenerationOption) (*Server, error) {
+	s := &Server{
+		idxOpt:  *idxOpt,
+		genOpt:  genOpt,
+		clients: make(map[chan struct{}]bool),
+	}
+	return s, s.Rebuild()
+}
+
+// Rebuild renders the site again and reloads the open pages. Pages failing
+// to be rendered are left out and reported in the returned error.
+func (s *Server) Rebuild() error {
+	// a fresh copy, so that ignore files are read again
+	idxOpt := s.idxOpt
+	idx, err := NewIndex(&idxOpt)
+	if idx == nil {
+		return err
+	}
+	return s.update(idx, err)
+}
+
+func (s *Server) update(idx *Index, err error) error {
+	files, renderErr := idx.renderSite(s.genOpt, s.genOpt.Nav)
+	m := make(map[string][]byte, len(files))
+	for _, f := range files {
+		m[f.path] = f.content
+	}
+
+	s.mu.Lock()
+	s.files = m
+	for c := range s.clients {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+	s.mu.Unlock()
+	return errors.Join(err, renderErr)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == reloadPath {
+		s.serveReload(w, r)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	if name == "" || strings.HasSuffix(name, "/") {
+		name += SiteIndexFile
+	}
+	s.mu.RLock()
+	b, ok := s.files[path.Clean(name)]
+	_, isDir := s.files[path.Join(name, SiteIndexFile)]
+	s.mu.RUnlock()
+	if !ok {
+		if isDir {
+			http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+	if path.Ext(name) == ".html" {
+		b = injectReload(b)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(b))
+}
+
+// serveReload sends an event to the page each time the site is rebuilt.
+func (s *Server) serveReload(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	c := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.clients[c] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, c)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-c:
+			fmt.Fprint(w, "data: reload\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// injectReload adds the live reload script to an html page.
+func injectReload(page []byte) []byte {
+	i := bytes.LastIndex(page, []byte("</body>"))
+	if i < 0 {
+		return append(page[:len(page):len(page)], reloadScript...)
+	}
+	result := make([]byte, 0, len(page)+len(reloadScript))
+	result = append(result, page[:i]...)
+	result = append(result, reloadScript...)
+	return append(result, page[i:]...)
+}
+
+// Serve serves the site of the work dir on addr until ctx is done. The
+// work dir is watched, and the site is rebuilt after each burst of changes.
+// ready is called with the address of the listener once it is listening.
+func Serve(ctx context.Context, addr string, idxOpt *IndexOption, genOpt *GenerationOption, ready func(addr string)) error {
+	w, err := newWatcher(idxOpt, genOpt, DefaultDebounce)
+	if err != nil {
+		return err
+	}
+	defer w.fsw.Close()
+
+	s, err := NewServer(&w.idxOpt, genOpt)
+	if err != nil {
+		fmt.Printf("ERROR: %s\n", err)
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: s}
+	go func() {
+		<-ctx.Done()
+		// the reload streams of open pages never end, close them too
+		srv.Close()
+	}()
+	if ready != nil {
+		ready(ln.Addr().String())
+	}
+
+	go w.run(ctx, func(changed []string) error {
+		if changed == nil {
+			// built above
+			return nil
+		}
+		idx, _, err := w.index(changed)
+		if idx == nil {
+			return err
+		}
+		err = s.update(idx, err)
+		if genOpt.Verbose {
+			fmt.Printf("OK: rebuilt site\n")
+		}
+		return err
+	})
+
+	if err := srv.Serve(ln); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".mdiignore":      "private\n",
+		"root.md":         "# Root\n",
+		"go/hello.md":     "# Hello\n\n![logo](logo.png)\n",
+		"go/vars.md":      "# Vars\n",
+		"go/logo.png":     "PNG",
+		"private/todo.md": "# Todo\n",
+	})
+	srv, err := NewServer(&IndexOption{WorkDir: dir, IndexTitle: "Notes", RootIndexFile: path.Join(dir, "README.md"), SubIndexFile: defaultIndexFile}, &GenerationOption{Nav: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	client := ts.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	get := func(p string) (int, string, string) {
+		t.Helper()
+		resp, err := client.Get(ts.URL + p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, resp.Header.Get("Content-Type"), string(b)
+	}
+
+	testdata := []struct {
+		path     string
+		status   int
+		expected string
+	}{
+		{"/", http.StatusOK, `<a href="go/index.html">go</a>`},
+		{"/go/", http.StatusOK, `<a href="hello.html">Hello</a>`},
+		{"/go", http.StatusMovedPermanently, ""},
+		{"/go/hello.html", http.StatusOK, `<nav class="breadcrumb"><a href="../index.html">Notes</a> / <a href="index.html">go</a> / Hello</nav>`},
+		{"/go/hello.html", http.StatusOK, reloadScript + "</body>"},
+		{"/go/logo.png", http.StatusOK, "PNG"},
+		{"/private/todo.html", http.StatusNotFound, ""},
+		{"/go/hello.md", http.StatusNotFound, ""},
+	}
+	for _, d := range testdata {
+		status, _, body := get(d.path)
+		if status != d.status || !strings.Contains(body, d.expected) {
+			t.Errorf("GET %s = %d, %s, expected %d with %s", d.path, status, body, d.status, d.expected)
+		}
+	}
+	if _, ct, _ := get("/go/vars.html"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("GET /go/vars.html Content-Type = %s, expected text/html", ct)
+	}
+
+	// open pages reload when the site is rebuilt
+	resp, err := client.Get(ts.URL + reloadPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := make(chan string)
+	go func() {
+		s := bufio.NewScanner(resp.Body)
+		for s.Scan() {
+			if s.Text() != "" {
+				events <- s.Text()
+			}
+		}
+	}()
+
+	writeTree(t, dir, map[string]string{"go/new.md": "# New\n"})
+	for i := 0; ; i++ {
+		// the stream may not be registered yet
+		srv.mu.RLock()
+		n := len(srv.clients)
+		srv.mu.RUnlock()
+		if n > 0 || i == 100 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := srv.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case ev := <-events:
+		if ev != "data: reload" {
+			t.Errorf("reload event = %q, expected data: reload", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("no reload event after Rebuild()")
+	}
+	if status, _, body := get("/go/new.html"); status != http.StatusOK || !strings.Contains(body, `<a class="prev" href="hello.html">`) {
+		t.Errorf("GET /go/new.html = %d, %s, expected the new page", status, body)
+	}
+}
+
+func TestServerNav(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"go/hello.md": "# Hello\n", "go/vars.md": "# Vars\n"})
+	srv, err := NewServer(&IndexOption{WorkDir: dir, SubIndexFile: defaultIndexFile}, &GenerationOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := string(srv.files["go/hello.html"])
+	if strings.Contains(b, `class="breadcrumb"`) || strings.Contains(b, `class="pager"`) {
+		t.Errorf("page without nav = %s, expected no breadcrumb and pager", b)
+	}
+}
+
+func TestServerDecorated(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"go/hello.md": "# Hello\n\nSee [Vars](vars.md).\n", "go/vars.md": "# Vars\n"})
+	idxOpt := &IndexOption{WorkDir: dir, IndexTitle: "Notes", RootIndexFile: path.Join(dir, "README.md"), SubIndexFile: defaultIndexFile}
+	idx, err := NewIndex(idxOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Generate(&GenerationOption{Recursive: true, Nav: true, Backlinks: true}); err != nil {
+		t.Fatal(err)
+	}
+	srv, err := NewServer(idxOpt, &GenerationOption{Nav: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the nav written by gen is not served twice
+	testdata := []struct {
+		page     string
+		expected string
+		count    int
+	}{
+		{"go/hello.html", `<a href="index.html">go</a>`, 1},
+		{"go/hello.html", `» Vars`, 0},
+		{"go/vars.html", `<a href="index.html">go</a>`, 1},
+		{"go/vars.html", `« Hello`, 1},
+		{"go/vars.html", `Linked from`, 0},
+	}
+	for _, d := range testdata {
+		b := string(srv.files[d.page])
+		if n := strings.Count(b, d.expected); n != d.count {
+			t.Errorf("page %s = %s, expected %d of %s, got %d", d.page, b, d.count, d.expected, n)
+		}
+	}
+}
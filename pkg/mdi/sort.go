@@ -0,0 +1,217 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Sort keys, combined as a comma separated list where later keys break
+// ties of earlier ones. A `-` prefix reverses a key.
+const (
+	// SortOrder follows the `order` list of `.mdi.yaml`.
+	SortOrder = "order"
+	// SortWeight follows `weight` or `order` in front matter.
+	SortWeight = "weight"
+	// SortName is the lexical order of file names.
+	SortName = "name"
+	// SortNatural is the order of file names with numbers compared by value.
+	SortNatural = "natural"
+	// SortTitle is the natural order of titles.
+	SortTitle = "title"
+	// SortModTime is the order of modification time, oldest first.
+	SortModTime = "mtime"
+	// SortGit is the order of last git commit date, oldest first.
+	SortGit = "git"
+)
+
+// DefaultSort keeps the `order` list first, then weighted files, then file names.
+const DefaultSort = SortOrder + "," + SortWeight + "," + SortName
+
+var sortKeys = []string{SortOrder, SortWeight, SortName, SortNatural, SortTitle, SortModTime, SortGit}
+
+// ValidateSort checks every key of a sort option.
+func ValidateSort(sort string) error {
+	for _, key := range parseSort(sort) {
+		if !slices.Contains(sortKeys, strings.TrimPrefix(key, "-")) {
+			return fmt.Errorf("invalid sort key: %s, available keys: %s", key, strings.Join(sortKeys, ", "))
+		}
+	}
+	return nil
+}
+
+func parseSort(sort string) []string {
+	if strings.TrimSpace(sort) == "" {
+		sort = DefaultSort
+	}
+	var keys []string
+	for _, key := range strings.Split(sort, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// sortItem is what sort keys compare, for both entries and sub indexes.
+type sortItem struct {
+	name  string
+	title string
+	file  string
+	meta  *frontMatter
+	mtime time.Time
+	git   time.Time
+}
+
+func indexSortItem(idx *Index) sortItem {
+	return sortItem{name: path.Base(idx.workDir), title: idx.title, file: idx.workDir, meta: idx.meta}
+}
+
+func entrySortItem(e *Entry) sortItem {
+	return sortItem{name: path.Base(e.file), title: e.title, file: e.file, meta: e.meta}
+}
+
+// sortItems stable sorts items by the keys of sort.
+func sortItems[T any](c *cache, items []T, sort string, order []string, item func(T) sortItem) {
+	keys := parseSort(sort)
+
+	sorted := make([]sortItem, len(items))
+	for i := range items {
+		sorted[i] = item(items[i])
+	}
+	for _, key := range keys {
+		switch strings.TrimPrefix(key, "-") {
+		case SortModTime:
+			for i := range sorted {
+				if fi, err := fs.Stat(c.fsys, sorted[i].file); err == nil {
+					sorted[i].mtime = fi.ModTime()
+				}
+			}
+		case SortGit:
+			if len(sorted) > 0 {
+				history := c.gitHistory(path.Dir(sorted[0].file))
+				for i := range sorted {
+					if commit := history.lastCommit(sorted[i].file); commit != nil {
+						sorted[i].git = commit.When
+					}
+				}
+			}
+		}
+	}
+
+	// Stable sorts from the last key to the first, so that later keys only
+	// break ties of earlier ones.
+	perm := make([]int, len(items))
+	for i := range perm {
+		perm[i] = i
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		switch key := keys[i]; key {
+		case SortOrder:
+			sortByOrder(perm, order, func(p int) string { return sorted[p].name })
+		case SortWeight:
+			sortByWeight(perm, func(p int) *frontMatter { return sorted[p].meta })
+		default:
+			slices.SortStableFunc(perm, func(a, b int) int {
+				return compareBy(key, order, sorted[a], sorted[b])
+			})
+		}
+	}
+
+	result := make([]T, len(items))
+	for i, p := range perm {
+		result[i] = items[p]
+	}
+	copy(items, result)
+}
+
+func compareBy(key string, order []string, a, b sortItem) int {
+	if desc, ok := strings.CutPrefix(key, "-"); ok {
+		return -compareBy(desc, order, a, b)
+	}
+
+	switch key {
+	case SortOrder:
+		return orderRank(order, a.name) - orderRank(order, b.name)
+	case SortWeight:
+		return compareWeight(a.meta, b.meta)
+	case SortName:
+		return strings.Compare(a.name, b.name)
+	case SortNatural:
+		return naturalCompare(a.name, b.name)
+	case SortTitle:
+		return naturalCompare(strings.ToLower(a.title), strings.ToLower(b.title))
+	case SortModTime:
+		return compareTime(a.mtime, b.mtime)
+	case SortGit:
+		return compareTime(a.git, b.git)
+	}
+	return 0
+}
+
+// compareTime compares times, zero time (unknown) comes last.
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.IsZero() && b.IsZero():
+		return 0
+	case a.IsZero():
+		return 1
+	case b.IsZero():
+		return -1
+	}
+	return a.Compare(b)
+}
+
+// naturalCompare compares strings with runs of digits compared by their
+// numeric value, so that `2-setup.md` sorts before `10-intro.md`.
+func naturalCompare(a, b string) int {
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da != "" && db != "" {
+			na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+			if c := len(na) - len(nb); c != 0 {
+				return c
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			if c := len(da) - len(db); c != 0 {
+				return c
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return int(a[0]) - int(b[0])
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) - len(b)
+}
+
+func digitPrefix(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
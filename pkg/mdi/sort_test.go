@@ -0,0 +1,157 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"os"
+	"path"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestNaturalCompare(t *testing.T) {
+	testdata := []struct {
+		a, b     string
+		expected int
+	}{
+		{"2-setup.md", "10-intro.md", -1},
+		{"10-intro.md", "2-setup.md", 1},
+		{"a2.md", "a02.md", -1},
+		{"a.md", "a.md", 0},
+		{"a.md", "b.md", -1},
+		{"v1.10", "v1.9", 1},
+	}
+
+	for _, d := range testdata {
+		actual := naturalCompare(d.a, d.b)
+		if (actual < 0 && d.expected >= 0) || (actual > 0 && d.expected <= 0) || (actual == 0 && d.expected != 0) {
+			t.Errorf("naturalCompare(%q, %q) = %d, expected sign of %d", d.a, d.b, actual, d.expected)
+		}
+	}
+}
+
+func TestSortItems(t *testing.T) {
+	weight := 1
+	items := []sortItem{
+		{name: "10-intro.md", title: "Intro"},
+		{name: "2-setup.md", title: "setup"},
+		{name: "1-start.md", title: "Start"},
+		{name: "appendix.md", title: "Appendix", meta: &frontMatter{Weight: &weight}},
+		{name: "faq.md", title: "FAQ"},
+	}
+	names := func(items []sortItem) []string {
+		var result []string
+		for _, item := range items {
+			result = append(result, item.name)
+		}
+		return result
+	}
+	for i := range items {
+		if items[i].meta == nil {
+			items[i].meta = &frontMatter{}
+		}
+	}
+
+	testdata := []struct {
+		sort     string
+		order    []string
+		expected []string
+	}{
+		{"", nil, []string{"appendix.md", "1-start.md", "10-intro.md", "2-setup.md", "faq.md"}},
+		{"", []string{"faq.md"}, []string{"faq.md", "appendix.md", "1-start.md", "10-intro.md", "2-setup.md"}},
+		{"natural", nil, []string{"1-start.md", "2-setup.md", "10-intro.md", "appendix.md", "faq.md"}},
+		{"-natural", nil, []string{"faq.md", "appendix.md", "10-intro.md", "2-setup.md", "1-start.md"}},
+		{"title", nil, []string{"appendix.md", "faq.md", "10-intro.md", "2-setup.md", "1-start.md"}},
+		{"weight,natural", nil, []string{"appendix.md", "1-start.md", "2-setup.md", "10-intro.md", "faq.md"}},
+	}
+
+	for _, d := range testdata {
+		sorted := slices.Clone(items)
+		sortItems(newCache(OSFS{}, 0), sorted, d.sort, d.order, func(item sortItem) sortItem { return item })
+		if actual := names(sorted); !slices.Equal(actual, d.expected) {
+			t.Errorf("sortItems(%q, %v) = %v, expected %v", d.sort, d.order, actual, d.expected)
+		}
+	}
+}
+
+func TestValidateSort(t *testing.T) {
+	for _, sort := range []string{"", DefaultSort, "natural", "-git, name"} {
+		if err := ValidateSort(sort); err != nil {
+			t.Errorf("ValidateSort(%q) = %v, expected nil", sort, err)
+		}
+	}
+	if err := ValidateSort("order,size"); err == nil {
+		t.Errorf("ValidateSort(%q) = nil, expected error", "order,size")
+	}
+}
+
+func TestReadGitHistory(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit := func(file, author string, when time.Time) {
+		if err := os.MkdirAll(path.Join(dir, path.Dir(file)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path.Join(dir, file), []byte("# "+file+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add(file); err != nil {
+			t.Fatal(err)
+		}
+		sig := &object.Signature{Name: author, Email: author + "@example.com", When: when}
+		if _, err := wt.Commit("update "+file, &git.CommitOptions{Author: sig, Committer: sig}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+	commit("go/basics.md", "alice", first)
+	commit("rust/intro.md", "bob", second)
+
+	history := readGitHistory(dir)
+	testdata := []struct {
+		file   string
+		author string
+		when   time.Time
+	}{
+		{"go/basics.md", "alice", first},
+		{"go", "alice", first},
+		{"rust/intro.md", "bob", second},
+		{"rust", "bob", second},
+	}
+	for _, d := range testdata {
+		c := history.lastCommit(path.Join(dir, d.file))
+		if c == nil || c.Author != d.author || !c.When.Equal(d.when) {
+			t.Errorf("lastCommit(%q) = %+v, expected %s at %s", d.file, c, d.author, d.when)
+		}
+	}
+	if c := history.lastCommit(path.Join(dir, "missing.md")); c != nil {
+		t.Errorf("lastCommit(missing.md) = %+v, expected nil", c)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/poneding/mdi/pkg/util"
+)
+
+// renderSummary renders the index tree in mdBook SUMMARY.md syntax: the
+// root index file is the prefix chapter, root entries are numbered
+// chapters, and every top-level directory is a part. Directories without
+// an index file on disk are draft chapters.
+func (idx *Index) renderSummary() string {
+	var sb strings.Builder
+	sb.WriteString("# Summary\n")
+
+	if idx.exists(idx.file) {
+		fmt.Fprintf(&sb, "\n[%s](%s)\n", idx.title, idx.summaryLink(idx.file))
+	}
+	if len(idx.entries) > 0 {
+		sb.WriteString("\n")
+		for _, entry := range idx.entries {
+			idx.writeSummaryItem(&sb, 0, entry.title, entry.file)
+		}
+	}
+	for _, part := range idx.children {
+		fmt.Fprintf(&sb, "\n# %s\n\n", part.title)
+		if idx.exists(part.file) {
+			idx.writeSummaryItem(&sb, 0, part.title, part.file)
+		}
+		idx.writeSummaryItems(&sb, part, 0)
+	}
+	return sb.String()
+}
+
+// writeSummaryItems writes the chapters of the sub indexes and entries of subIdx.
+func (idx *Index) writeSummaryItems(sb *strings.Builder, subIdx *Index, depth int) {
+	for _, child := range subIdx.children {
+		idx.writeSummaryItem(sb, depth, child.title, util.If(idx.exists(child.file), child.file, ""))
+		idx.writeSummaryItems(sb, child, depth+1)
+	}
+	for _, entry := range subIdx.entries {
+		idx.writeSummaryItem(sb, depth, entry.title, entry.file)
+	}
+}
+
+// writeSummaryItem writes a numbered chapter, a draft chapter if file is empty.
+func (idx *Index) writeSummaryItem(sb *strings.Builder, depth int, title, file string) {
+	fmt.Fprintf(sb, "%s- [%s](%s)\n", strings.Repeat("  ", depth), title, util.If(file != "", idx.summaryLink(file), ""))
+}
+
+// summaryLink is the path of file relative to the work dir, mdBook takes
+// paths as is, so paths with spaces are wrapped in angle brackets.
+func (idx *Index) summaryLink(file string) string {
+	rel := idx.relPath(file)
+	if strings.ContainsAny(rel, " ()") {
+		return "<" + rel + ">"
+	}
+	return rel
+}
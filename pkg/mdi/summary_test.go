@@ -0,0 +1,88 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestRenderSummary(t *testing.T) {
+	idx := newTestIndex(t, map[string]string{
+		"README.md":                   "# Notes\n",
+		"root.md":                     "# Root\n",
+		"go/zz_generated_mdi.md":      "# Go\n",
+		"go/hello.md":                 "# Hello\n",
+		"go/basics/intro.md":          "# Intro\n",
+		"my notes/a note.md":          "# A Note\n",
+		"my notes/drafts/wip/todo.md": "# Todo\n",
+	})
+
+	expected := `# Summary
+
+[Notes](README.md)
+
+- [Root](root.md)
+
+# Go
+
+- [Go](go/zz_generated_mdi.md)
+- [basics]()
+  - [Intro](go/basics/intro.md)
+- [Hello](go/hello.md)
+
+# my notes
+
+- [drafts]()
+  - [wip]()
+    - [Todo](<my notes/drafts/wip/todo.md>)
+- [A Note](<my notes/a note.md>)
+`
+	if actual := idx.renderSummary(); actual != expected {
+		t.Errorf("renderSummary() = %q, expected %q", actual, expected)
+	}
+}
+
+func TestPlanSummary(t *testing.T) {
+	idx := newTestIndex(t, map[string]string{"a.md": "# A\n", "sub/b.md": "# B\n"})
+	file := path.Join(idx.workDir, SummaryFile)
+
+	testdata := []struct {
+		existing string
+		override bool
+		expected ChangeKind
+		changes  int
+	}{
+		{"", false, Created, 1},
+		{"# My Summary\n", false, Unchanged, 0},
+		{"# My Summary\n", true, Modified, 1},
+	}
+	for _, d := range testdata {
+		os.Remove(file)
+		if d.existing != "" {
+			os.WriteFile(file, []byte(d.existing), 0644)
+		}
+		p, err := idx.Plan(&GenerationOption{Format: FormatSummary, Override: d.override, Recursive: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(p.Changes) != d.changes || (d.changes > 0 && (p.Changes[0].File != file || p.Changes[0].Kind != d.expected)) {
+			t.Errorf("Plan(override=%v) with existing %q = %v, expected %d change(s) of %s", d.override, d.existing, p.Changes, d.changes, d.expected)
+		}
+	}
+}
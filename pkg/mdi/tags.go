@@ -0,0 +1,248 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// The tags index and the tag pages, written in the work dir of the root
+// index with GenerationOption.Tags.
+const (
+	TagsFile = "tags.md"
+	TagsDir  = "tags"
+	// TagsTitle is the title of the tags index.
+	TagsTitle = "Tags"
+)
+
+// generatedFrontMatter marks the tag pages, so that they are left out of
+// the index tree and removed by Clean.
+const generatedFrontMatter = "---\n" + generatedRule + "\n---\n\n"
+
+// inlineTag matches the `#tag` tokens of a text, a tag has a non-digit
+// character and may be nested like `#lang/go`.
+var inlineTag = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_][\p{L}\p{N}_/-]*)`)
+
+// parseInlineTags returns the `#tag` tokens of src, those in code and
+// links are ignored.
+func parseInlineTags(md goldmark.Markdown, src []byte) []string {
+	var tags []string
+	ast.Walk(md.Parser().Parse(text.NewReader(src)), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.CodeSpan, *ast.Link, *ast.AutoLink:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			for _, m := range inlineTag.FindAllSubmatch(n.Segment.Value(src), -1) {
+				tag := strings.TrimRight(string(m[1]), "/")
+				if strings.IndexFunc(tag, func(r rune) bool { return !unicode.IsDigit(r) }) >= 0 {
+					tags = append(tags, tag)
+				}
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return tags
+}
+
+// readTags returns the tags of the entry, in front matter first, without
+// duplicates.
+func (e *Entry) readTags(md goldmark.Markdown) ([]string, error) {
+	b, err := fs.ReadFile(e.index.fsys, e.file)
+	if err != nil {
+		return nil, newError("read file", e.file, err)
+	}
+	content, _ := normalizeEOL(string(b))
+	_, body := splitFrontMatter(content)
+
+	var tags []string
+	for _, tag := range append(slices.Clone(e.meta.Tags), parseInlineTags(md, []byte(body))...) {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// tagFile returns the name of the page of tag in the tags dir, the
+// characters of tag other than letters, digits, `-` and `_` are replaced.
+func tagFile(tag string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, tag) + ".md"
+}
+
+// tagFiles returns the page names of tags, a name already taken by a
+// previous tag, regardless of case, gets a `-2`, `-3`... suffix.
+func tagFiles(tags []string) map[string]string {
+	files := make(map[string]string, len(tags))
+	taken := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		file := tagFile(tag)
+		stem := strings.TrimSuffix(file, ".md")
+		for n := 2; taken[strings.ToLower(file)]; n++ {
+			file = fmt.Sprintf("%s-%d.md", stem, n)
+		}
+		taken[strings.ToLower(file)] = true
+		files[tag] = file
+	}
+	return files
+}
+
+// planTags collects the tags index and the tag pages of the tree of the
+// root index, and the removal of the generated pages of the tags gone. An
+// existing page not generated by mdi is kept unless Override is set.
+func (idx *Index) planTags(p *Plan, genOpt *GenerationOption) error {
+	md := newMarkdown()
+	var errs []error
+	var tags []string
+	tagged := make(map[string][]*Entry)
+	idx.Walk(func(i *Index) error {
+		for _, entry := range i.entries {
+			entryTags, err := entry.readTags(md)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, tag := range entryTags {
+				if tagged[tag] == nil {
+					tags = append(tags, tag)
+				}
+				tagged[tag] = append(tagged[tag], entry)
+			}
+		}
+		return nil
+	})
+	slices.SortFunc(tags, func(a, b string) int {
+		if c := strings.Compare(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	files := tagFiles(tags)
+
+	var history gitHistory
+	if genOpt.LastUpdated && idx.cache != nil {
+		history = idx.cache.gitHistory(idx.workDir)
+	}
+	t, err := genOpt.templates()
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	home := path.Base(idx.file)
+
+	data := &TagsData{Title: TagsTitle}
+	for _, tag := range tags {
+		data.Tags = append(data.Tags, &TagItem{Name: tag, Link: getLink(TagsDir + "/" + files[tag]), Count: len(tagged[tag])})
+	}
+	breadcrumb, err := execTemplate(t.breadcrumb, &BreadcrumbData{
+		Crumbs: []*Link{{Title: idx.homeTitle, Link: getLink(home)}},
+		Title:  TagsTitle,
+	})
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	data.Breadcrumb = breadcrumb
+	content, err := execTemplate(t.tags, data)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	errs = append(errs, idx.addGenerated(p, genOpt, path.Join(idx.workDir, TagsFile), content))
+
+	tagsDir := path.Join(idx.workDir, TagsDir)
+	crumbs := []*Link{{Title: idx.homeTitle, Link: getLink("../" + home)}, {Title: TagsTitle, Link: "../" + TagsFile}}
+	for _, tag := range tags {
+		page := &IndexData{Title: tag}
+		if page.Breadcrumb, err = execTemplate(t.breadcrumb, &BreadcrumbData{Crumbs: crumbs, Title: tag}); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, entry := range tagged[tag] {
+			page.Entries = append(page.Entries, entry.indexItem(tagsDir, 0, history))
+		}
+		content, err := execTemplate(t.index, page)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, idx.addGenerated(p, genOpt, path.Join(tagsDir, files[tag]), content))
+	}
+	errs = append(errs, idx.removeStaleTags(p, tagsDir, files))
+	return errors.Join(errs...)
+}
+
+// removeStaleTags adds the removal of the pages generated by mdi in tagsDir
+// which are not one of the pages of tagPages.
+func (idx *Index) removeStaleTags(p *Plan, tagsDir string, tagPages map[string]string) error {
+	pages := make(map[string]bool, len(tagPages))
+	for _, page := range tagPages {
+		pages[page] = true
+	}
+	files, err := fs.ReadDir(idx.fsys, tagsDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return newError("read dir", tagsDir, err)
+	}
+
+	var errs []error
+	for _, f := range files {
+		if f.IsDir() || !isMarkdown(f.Name()) || pages[f.Name()] {
+			continue
+		}
+		file := path.Join(tagsDir, f.Name())
+		b, err := fs.ReadFile(idx.fsys, file)
+		if err != nil {
+			errs = append(errs, newError("read file", file, err))
+			continue
+		}
+		if parseFrontMatter(string(b)).Generated {
+			errs = append(errs, p.remove(file))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// addGenerated adds a page generated by mdi to the plan, unless the
+// existing file is a hand-written one and Override is not set.
+func (idx *Index) addGenerated(p *Plan, genOpt *GenerationOption, file, content string) error {
+	if b, err := fs.ReadFile(idx.fsys, file); err == nil && !parseFrontMatter(string(b)).Generated && !genOpt.Override {
+		if genOpt.Verbose {
+			fmt.Printf("SKIP: tag page conflict: %s, use --override=true to override it\n", file)
+		}
+		return nil
+	}
+	return p.add(file, []byte(generatedFrontMatter+content))
+}
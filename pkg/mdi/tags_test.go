@@ -0,0 +1,204 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestParseInlineTags(t *testing.T) {
+	testdata := []struct {
+		src      string
+		expected []string
+	}{
+		{"Notes on #go and #lang/go.\n", []string{"go", "lang/go"}},
+		{"#start of line\n\n## #heading\n", []string{"start", "heading"}},
+		{"Issue #123, a#b and [link](#anchor)\n", nil},
+		{"`#code` and\n\n```\n#fenced\n```\n", nil},
+		{"[#linked](hello.md) but #kept\n", []string{"kept"}},
+		{"# Title\n", nil},
+	}
+
+	md := newMarkdown()
+	for _, d := range testdata {
+		if tags := parseInlineTags(md, []byte(d.src)); !slices.Equal(tags, d.expected) {
+			t.Errorf("parseInlineTags(%q) = %q, expected %q", d.src, tags, d.expected)
+		}
+	}
+}
+
+func TestTagFile(t *testing.T) {
+	testdata := []struct {
+		tag      string
+		expected string
+	}{
+		{"go", "go.md"},
+		{"lang/go", "lang-go.md"},
+		{"C++", "C--.md"},
+		{"中文", "中文.md"},
+	}
+
+	for _, d := range testdata {
+		if file := tagFile(d.tag); file != d.expected {
+			t.Errorf("tagFile(%q) = %q, expected %q", d.tag, file, d.expected)
+		}
+	}
+}
+
+func TestTagFiles(t *testing.T) {
+	files := tagFiles([]string{"c++", "c--", "c---2", "Go", "go"})
+	expected := map[string]string{"c++": "c--.md", "c--": "c---2.md", "c---2": "c---2-2.md", "Go": "Go.md", "go": "go-2.md"}
+	if !maps.Equal(files, expected) {
+		t.Errorf("tagFiles() = %v, expected %v", files, expected)
+	}
+}
+
+func TestGenerateTagsCollision(t *testing.T) {
+	m := NewMemFS(map[string]string{
+		"README.md": "# Notes\n",
+		"cpp/a.md":  "---\ntags: [c++, Go]\n---\n# A\n",
+		"cpp/b.md":  "---\ntags: [c--, go]\n---\n# B\n",
+	})
+	idxOpt := &IndexOption{FS: m, WorkDir: ".", RootIndexFile: "README.md", SubIndexFile: defaultIndexFile}
+	idx, err := NewIndex(idxOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genOpt := &GenerationOption{Override: true, Recursive: true, Tags: true}
+	if err := idx.Generate(genOpt); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{"tags/c--.md", "tags/c---2.md", "tags/Go.md", "tags/go-2.md"} {
+		if _, err := fs.Stat(m, file); err != nil {
+			t.Errorf("Stat(%q) = %v, expected a page of each tag", file, err)
+		}
+	}
+
+	// the tree is up to date once generated
+	if idx, err = NewIndex(idxOpt); err != nil {
+		t.Fatal(err)
+	}
+	p, err := idx.Plan(genOpt)
+	if err != nil || p.Count(Created)+p.Count(Modified)+p.Count(Deleted) != 0 {
+		t.Errorf("Plan() = %d created, %d modified, %d deleted files, %v, expected none", p.Count(Created), p.Count(Modified), p.Count(Deleted), err)
+	}
+
+	// a file is changed at most once
+	if err := p.add("tags/Go.md", nil); err == nil {
+		t.Errorf("add(%q) = nil, expected an error for a file already planned", "tags/Go.md")
+	}
+}
+
+func TestGenerateTags(t *testing.T) {
+	m := NewMemFS(map[string]string{
+		"README.md":     "# Notes\n",
+		"go/hello.md":   "---\ntags: [go, cli]\n---\n# Hello\n\nA #cli tool.\n",
+		"go/draft.md":   "---\ndraft: true\ntags: go\n---\n# Draft\n",
+		"rust/intro.md": "---\ntags: rust, cli\n---\n# Intro\n",
+	})
+	idxOpt := &IndexOption{FS: m, WorkDir: ".", IndexTitle: "Notes", RootIndexFile: "README.md", SubIndexFile: defaultIndexFile}
+	idx, err := NewIndex(idxOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genOpt := &GenerationOption{Override: true, Recursive: true, Tags: true}
+	if err := idx.Generate(genOpt); err != nil {
+		t.Fatal(err)
+	}
+
+	testdata := []struct {
+		file     string
+		expected string
+	}{
+		{TagsFile, generatedFrontMatter + "[Notes](README.md) / Tags\n\n# Tags\n\n- [cli](tags/cli.md) (2)\n- [go](tags/go.md) (1)\n- [rust](tags/rust.md) (1)\n"},
+		{"tags/cli.md", generatedFrontMatter + "[Notes](../README.md) / [Tags](../tags.md) / cli\n\n# cli\n\n[Hello](../go/hello.md)\n\n[Intro](../rust/intro.md)\n"},
+		{"tags/go.md", generatedFrontMatter + "[Notes](../README.md) / [Tags](../tags.md) / go\n\n# go\n\n[Hello](../go/hello.md)\n"},
+	}
+	for _, d := range testdata {
+		if b, err := fs.ReadFile(m, d.file); err != nil || string(b) != d.expected {
+			t.Errorf("ReadFile(%q) = %q, %v, expected %q", d.file, b, err, d.expected)
+		}
+	}
+
+	// the tag pages are left out of the tree
+	idx, err = NewIndex(idxOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := idx.Plan(genOpt)
+	if err != nil || p.Count(Created)+p.Count(Modified) != 0 {
+		t.Errorf("Plan() = %d created, %d modified files, %v, expected none", p.Count(Created), p.Count(Modified), err)
+	}
+
+	// the pages of the tags gone are removed, hand-written ones are kept
+	if err := m.WriteFile("rust/intro.md", []byte("---\ntags: cli\n---\n# Intro\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.WriteFile("tags/notes.md", []byte("# Notes on tags\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if idx, err = NewIndex(idxOpt); err != nil {
+		t.Fatal(err)
+	}
+	if p, err = idx.Plan(genOpt); err != nil || p.Count(Deleted) != 1 {
+		t.Errorf("Plan() = %d deleted files, %v, expected 1", p.Count(Deleted), err)
+	}
+	if err := p.Apply(genOpt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(m, "tags/rust.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat(%q) = %v, expected the page of a tag gone to be removed", "tags/rust.md", err)
+	}
+	if _, err := fs.Stat(m, "tags/notes.md"); err != nil {
+		t.Errorf("Stat(%q) = %v, expected a hand-written page to be kept", "tags/notes.md", err)
+	}
+
+	if err := CleanFS(m, ".", defaultIndexFile); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{TagsFile, "tags/cli.md"} {
+		if _, err := fs.Stat(m, file); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Stat(%q) = %v after CleanFS, expected a not exist error", file, err)
+		}
+	}
+}
+
+func TestGenerateTagsConflict(t *testing.T) {
+	m := NewMemFS(map[string]string{
+		"README.md":   "# Notes\n",
+		"tags.md":     "# My tags\n",
+		"go/hello.md": "---\ntags: go\n---\n# Hello\n",
+	})
+	idx, err := NewIndex(&IndexOption{FS: m, WorkDir: ".", RootIndexFile: "README.md", SubIndexFile: defaultIndexFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := idx.Plan(&GenerationOption{Tags: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range p.Changes {
+		if c.File == TagsFile {
+			t.Errorf("Plan() changes %q, expected the hand-written tags index to be kept", TagsFile)
+		}
+	}
+}
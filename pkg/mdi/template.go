@@ -0,0 +1,353 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"embed"
+	"errors"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Template files, looked up in the template dir given to LoadTemplates.
+const (
+	IndexTemplate      = "index.tmpl"
+	BreadcrumbTemplate = "breadcrumb.tmpl"
+	FooterTemplate     = "footer.tmpl"
+	TagsTemplate       = "tags.tmpl"
+	BacklinksTemplate  = "backlinks.tmpl"
+	// PageTemplate is the html/template of the pages of Build.
+	PageTemplate = "page.html"
+)
+
+//go:embed templates/*
+var defaultTemplates embed.FS
+
+// Templates render the index page, the breadcrumb line, the footer nav, the
+// tags index and the backlinks section, and the html pages of Build.
+type Templates struct {
+	index      *template.Template
+	breadcrumb *template.Template
+	footer     *template.Template
+	tags       *template.Template
+	backlinks  *template.Template
+	page       *htmltemplate.Template
+}
+
+// Link is a title linking to a path relative to the rendered file.
+type Link struct {
+	Title string
+	Link  string
+}
+
+// IndexData is the data of the index page template.
+type IndexData struct {
+	// Title is the title of the index.
+	Title string
+	// Breadcrumb is the rendered breadcrumb line, empty for the root index.
+	Breadcrumb string
+	// Region is true if the index is rendered between the index markers of
+	// a hand-written index file, which has a title of its own.
+	Region bool
+	// Children are the sub indexes, each with its own children and entries.
+	Children []*IndexItem
+	// Entries are the markdown files of the index.
+	Entries []*IndexItem
+	// Recent are the entries of the tree last changed in git, the most
+	// recent first, set for the root index only.
+	Recent []*IndexItem
+}
+
+// IndexItem is a sub index or an entry listed in the index page.
+type IndexItem struct {
+	Title string
+	// Link is relative to the index page, empty for the sub indexes of
+	// depth 0 when header links are disabled.
+	Link string
+	// Depth is 0 for the items of the index itself, 1 for the items of its
+	// sub indexes, and so on.
+	Depth int
+	// Children and Entries are set for sub indexes only.
+	Children []*IndexItem
+	Entries  []*IndexItem
+	// Updated and Author are the date and author of the last commit of
+	// entries, zero unless they are annotated.
+	Updated time.Time
+	Author  string
+}
+
+// BacklinksData is the data of the backlinks section template of entries.
+type BacklinksData struct {
+	// Links are the entries linking to the entry, in the order of the tree.
+	Links []*Link
+}
+
+// TagsData is the data of the tags index template, the tag pages are
+// rendered with the index page template.
+type TagsData struct {
+	Title      string
+	Breadcrumb string
+	// Tags are sorted by name.
+	Tags []*TagItem
+}
+
+// TagItem is a tag listed in the tags index.
+type TagItem struct {
+	Name string
+	// Link is the tag page, relative to the tags index.
+	Link string
+	// Count is the number of entries with the tag.
+	Count int
+}
+
+// BreadcrumbData is the data of the breadcrumb template, for both index
+// pages and entries.
+type BreadcrumbData struct {
+	// Crumbs link to the parent indexes, from the root index down.
+	Crumbs []*Link
+	// Title is the nav title of the current page.
+	Title string
+}
+
+// FooterData is the data of the footer nav template of entries.
+type FooterData struct {
+	// Prev and Next are the neighbor entries, nil at the ends.
+	Prev *Link
+	Next *Link
+}
+
+var templateFuncs = template.FuncMap{
+	"indent": func(n int) string { return strings.Repeat("  ", max(n, 0)) },
+	"add":    func(a, b int) int { return a + b },
+	"sub":    func(a, b int) int { return a - b },
+	"date":   func(t time.Time) string { return t.Format(time.DateOnly) },
+}
+
+// LoadTemplates loads the templates of dir, the built-in default is used
+// for every template file missing in dir. An empty dir loads the defaults.
+func LoadTemplates(dir string) (*Templates, error) {
+	return LoadTemplatesFS(OSFS{}, dir)
+}
+
+// LoadTemplatesFS is LoadTemplates on the filesystem fsys.
+func LoadTemplatesFS(fsys fs.FS, dir string) (*Templates, error) {
+	read := func(name string) (string, string, error) {
+		var b []byte
+		var err error
+		file := path.Join(dir, name)
+		if dir != "" {
+			b, err = fs.ReadFile(fsys, file)
+		}
+		if dir == "" || errors.Is(err, fs.ErrNotExist) {
+			file = path.Join("templates", name)
+			b, err = defaultTemplates.ReadFile(file)
+		}
+		if err != nil {
+			return "", file, newError("read file", file, err)
+		}
+		return string(b), file, nil
+	}
+	load := func(name string) (*template.Template, error) {
+		text, file, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+		t, err := template.New(name).Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			return nil, newError("parse template", file, err)
+		}
+		return t, nil
+	}
+
+	t := &Templates{}
+	var err error
+	if t.index, err = load(IndexTemplate); err != nil {
+		return nil, err
+	}
+	if t.breadcrumb, err = load(BreadcrumbTemplate); err != nil {
+		return nil, err
+	}
+	if t.footer, err = load(FooterTemplate); err != nil {
+		return nil, err
+	}
+	if t.tags, err = load(TagsTemplate); err != nil {
+		return nil, err
+	}
+	if t.backlinks, err = load(BacklinksTemplate); err != nil {
+		return nil, err
+	}
+	text, file, err := read(PageTemplate)
+	if err != nil {
+		return nil, err
+	}
+	if t.page, err = htmltemplate.New(PageTemplate).Funcs(htmltemplate.FuncMap(templateFuncs)).Parse(text); err != nil {
+		return nil, newError("parse template", file, err)
+	}
+	return t, nil
+}
+
+// builtinTemplates loads the embedded templates once, it is safe for
+// concurrent use.
+var builtinTemplates = sync.OnceValues(func() (*Templates, error) {
+	return LoadTemplates("")
+})
+
+// templates returns the templates of genOpt, the built-in ones if unset.
+func (genOpt *GenerationOption) templates() (*Templates, error) {
+	if genOpt.Templates != nil {
+		return genOpt.Templates, nil
+	}
+	return builtinTemplates()
+}
+
+func execTemplate(t *template.Template, data any) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", newError("execute template", t.Name(), err)
+	}
+	return sb.String(), nil
+}
+
+// renderIndex renders the index page, without the front matter, or the
+// index region of a hand-written index file.
+func (idx *Index) renderIndex(genOpt *GenerationOption, region bool) (string, error) {
+	t, err := genOpt.templates()
+	if err != nil {
+		return "", err
+	}
+	data := idx.indexData(genOpt)
+	data.Region = region
+	if len(idx.chains) > 1 {
+		breadcrumb, err := execTemplate(t.breadcrumb, &BreadcrumbData{
+			Crumbs: idx.crumbs(len(idx.chains) - 1),
+			Title:  idx.navTitle(),
+		})
+		if err != nil {
+			return "", err
+		}
+		data.Breadcrumb = breadcrumb
+	}
+	return execTemplate(t.index, data)
+}
+
+// indexData is the data of the index page, without breadcrumb.
+func (idx *Index) indexData(genOpt *GenerationOption) *IndexData {
+	// a single walk of the history for the whole run
+	var history, updated gitHistory
+	if (genOpt.LastUpdated || genOpt.Recent > 0) && idx.cache != nil {
+		history = idx.cache.gitHistory(idx.workDir)
+	}
+	if genOpt.LastUpdated {
+		updated = history
+	}
+
+	data := &IndexData{Title: idx.title}
+	data.Children, data.Entries = idx.indexItems(idx.workDir, 0, genOpt.NoHeaderLink, updated)
+	if genOpt.Recent > 0 && len(idx.chains) == 1 {
+		data.Recent = idx.recentItems(history, genOpt.Recent)
+	}
+	return data
+}
+
+func (idx *Index) indexItems(workDir string, depth int, noHeaderLink bool, history gitHistory) ([]*IndexItem, []*IndexItem) {
+	children := make([]*IndexItem, 0, len(idx.children))
+	for _, subIdx := range idx.children {
+		relPath, _ := filepath.Rel(workDir, subIdx.file)
+		item := &IndexItem{Title: subIdx.title, Link: getLink(relPath), Depth: depth}
+		if depth == 0 && noHeaderLink {
+			item.Link = ""
+		}
+		item.Children, item.Entries = subIdx.indexItems(workDir, depth+1, noHeaderLink, history)
+		children = append(children, item)
+	}
+
+	entries := make([]*IndexItem, 0, len(idx.entries))
+	for _, entry := range idx.entries {
+		entries = append(entries, entry.indexItem(workDir, depth, history))
+	}
+	return children, entries
+}
+
+// indexItem is the item of the entry, linked relative to workDir and
+// annotated with its last commit in history.
+func (e *Entry) indexItem(workDir string, depth int, history gitHistory) *IndexItem {
+	relPath, _ := filepath.Rel(workDir, e.file)
+	item := &IndexItem{Title: e.title, Link: getLink(relPath), Depth: depth}
+	if c := history.lastCommit(e.file); c != nil {
+		item.Updated, item.Author = c.When, c.Author
+	}
+	return item
+}
+
+// recentItems lists the n entries of the tree last changed in history, the
+// most recent first.
+func (idx *Index) recentItems(history gitHistory, n int) []*IndexItem {
+	var items []*IndexItem
+	idx.Walk(func(i *Index) error {
+		for _, entry := range i.entries {
+			if item := entry.indexItem(idx.workDir, 0, history); !item.Updated.IsZero() {
+				items = append(items, item)
+			}
+		}
+		return nil
+	})
+	slices.SortStableFunc(items, func(a, b *IndexItem) int {
+		return b.Updated.Compare(a.Updated)
+	})
+	return items[:min(n, len(items))]
+}
+
+// crumbs links to the first n indexes of the chains, relative to the dir of idx.
+func (idx *Index) crumbs(n int) []*Link {
+	var result []*Link
+	for i := 0; i < n; i++ {
+		title := idx.homeTitle
+		if i > 0 {
+			title = idx.chains[i].navTitle()
+		}
+		backpath := strings.Repeat("../", len(idx.chains)-i-1) + path.Base(idx.chains[i].file)
+		result = append(result, &Link{Title: title, Link: getLink(backpath)})
+	}
+	return result
+}
+
+// renderBreadcrumb renders the breadcrumb line of an entry.
+func (e *Entry) renderBreadcrumb(t *Templates) (string, error) {
+	return execTemplate(t.breadcrumb, &BreadcrumbData{
+		Crumbs: e.index.crumbs(len(e.index.chains)),
+		Title:  e.navTitle(),
+	})
+}
+
+// renderFooter renders the footer nav of an entry, empty if it has no neighbors.
+func (e *Entry) renderFooter(t *Templates) (string, error) {
+	data := &FooterData{}
+	if e.prev != nil {
+		data.Prev = &Link{Title: e.prev.navTitle(), Link: getLink(path.Base(e.prev.file))}
+	}
+	if e.next != nil {
+		data.Next = &Link{Title: e.next.navTitle(), Link: getLink(path.Base(e.next.file))}
+	}
+	return execTemplate(t.footer, data)
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"errors"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func newTestIndex(t *testing.T, files map[string]string) *Index {
+	t.Helper()
+	dir := t.TempDir()
+	writeTree(t, dir, files)
+	idx, err := NewIndex(&IndexOption{WorkDir: dir, IndexTitle: "Notes", RootIndexFile: path.Join(dir, "README.md"), SubIndexFile: defaultIndexFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return idx
+}
+
+func TestDefaultTemplates(t *testing.T) {
+	idx := newTestIndex(t, map[string]string{
+		"root.md":            "# Root\n",
+		"go/hello.md":        "# Hello\n",
+		"go/vars.md":         "# Vars\n",
+		"go/basics/intro.md": "# Intro\n",
+		"my notes/a note.md": "# A Note\n",
+	})
+	templates, err := LoadTemplates("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testdata := []struct {
+		idx          *Index
+		noHeaderLink bool
+		expected     string
+	}{
+		{idx, false, "# Notes\n\n## [go](go/zz_generated_mdi.md)\n\n- [basics](go/basics/zz_generated_mdi.md)\n  - [Intro](go/basics/intro.md)\n\n- [Hello](go/hello.md)\n- [Vars](go/vars.md)\n\n## [my notes](my%20notes/zz_generated_mdi.md)\n\n- [A Note](my%20notes/a%20note.md)\n\n[Root](root.md)\n"},
+		{idx, true, "# Notes\n\n## go\n\n- [basics](go/basics/zz_generated_mdi.md)\n  - [Intro](go/basics/intro.md)\n\n- [Hello](go/hello.md)\n- [Vars](go/vars.md)\n\n## my notes\n\n- [A Note](my%20notes/a%20note.md)\n\n[Root](root.md)\n"},
+		{idx.children[0].children[0], false, "[Notes](../../README.md) / [go](../zz_generated_mdi.md) / basics\n\n# basics\n\n[Intro](intro.md)\n"},
+	}
+	for _, d := range testdata {
+		actual, err := d.idx.renderIndex(&GenerationOption{Templates: templates, NoHeaderLink: d.noHeaderLink}, false)
+		if err != nil || actual != d.expected {
+			t.Errorf("renderIndex(%q, %v) = %q, %v, expected %q", d.idx.title, d.noHeaderLink, actual, err, d.expected)
+		}
+	}
+
+	hello, vars := idx.children[0].entries[0], idx.children[0].entries[1]
+	if actual, _ := hello.renderBreadcrumb(templates); actual != "[Notes](../README.md) / [go](zz_generated_mdi.md) / Hello" {
+		t.Errorf("renderBreadcrumb() = %q, unexpected", actual)
+	}
+	if actual, _ := hello.renderFooter(templates); actual != "---\n[» Vars](vars.md)\n" {
+		t.Errorf("renderFooter() = %q, unexpected", actual)
+	}
+	if actual, _ := vars.renderFooter(templates); actual != "---\n[« Hello](hello.md)\n" {
+		t.Errorf("renderFooter() = %q, unexpected", actual)
+	}
+	if actual, _ := idx.entries[0].renderFooter(templates); actual != "" {
+		t.Errorf("renderFooter() = %q, expected empty footer without neighbors", actual)
+	}
+}
+
+func TestLastUpdated(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	commitTree(t, repo, dir, map[string]string{"go/hello.md": "# Hello\n", "go/vars.md": "# Vars\n", "root.md": "# Root\n"}, first)
+	commitTree(t, repo, dir, map[string]string{"go/vars.md": "# Vars\n\nchanged\n"}, first.Add(48*time.Hour))
+	commitTree(t, repo, dir, map[string]string{"root.md": "# Root\n\nchanged\n"}, first.Add(24*time.Hour))
+	// not committed
+	writeTree(t, dir, map[string]string{"go/new.md": "# New\n"})
+
+	idx, err := NewIndex(&IndexOption{WorkDir: dir, IndexTitle: "Notes", RootIndexFile: path.Join(dir, "README.md"), SubIndexFile: defaultIndexFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testdata := []struct {
+		idx      *Index
+		genOpt   *GenerationOption
+		expected string
+	}{
+		{idx, &GenerationOption{Recent: 2}, "# Notes\n\n## Recently updated\n\n- [Vars](go/vars.md) _(2023-01-03 by alice)_\n- [Root](root.md) _(2023-01-02 by alice)_\n\n## [go](go/zz_generated_mdi.md)\n\n- [Hello](go/hello.md)\n- [New](go/new.md)\n- [Vars](go/vars.md)\n\n[Root](root.md)\n"},
+		{idx.children[0], &GenerationOption{LastUpdated: true, Recent: 2}, "[Notes](../README.md) / go\n\n# go\n\n[Hello](hello.md) _(2023-01-01 by alice)_\n\n[New](new.md)\n\n[Vars](vars.md) _(2023-01-03 by alice)_\n"},
+	}
+	for _, d := range testdata {
+		actual, err := d.idx.renderIndex(d.genOpt, false)
+		if err != nil || actual != d.expected {
+			t.Errorf("renderIndex(%q, %+v) = %q, %v, expected %q", d.idx.title, d.genOpt, actual, err, d.expected)
+		}
+	}
+}
+
+func TestLoadTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		FooterTemplate: "{{with .Next}}Next: [{{.Title}}]({{.Link}}){{end}}",
+	})
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx := newTestIndex(t, map[string]string{"a.md": "# A\n", "b.md": "# B\n"})
+	if actual, _ := idx.entries[0].renderFooter(templates); actual != "Next: [B](b.md)" {
+		t.Errorf("renderFooter() = %q, expected the custom footer", actual)
+	}
+	// missing files fall back to the built-in templates
+	if actual, _ := idx.entries[0].renderBreadcrumb(templates); actual != "[Notes](README.md) / A" {
+		t.Errorf("renderBreadcrumb() = %q, expected the built-in breadcrumb", actual)
+	}
+
+	writeTree(t, dir, map[string]string{IndexTemplate: "{{range .Entries}"})
+	var e *Error
+	if _, err := LoadTemplates(dir); !errors.As(err, &e) || e.Path != path.Join(dir, IndexTemplate) {
+		t.Errorf("LoadTemplates() = %v, expected a parse error on %s", err, IndexTemplate)
+	}
+}
+
+func TestBuiltinTemplatesConcurrent(t *testing.T) {
+	results := make([]*Templates, 8)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tmpl, err := (&GenerationOption{}).templates()
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = tmpl
+		}(i)
+	}
+	wg.Wait()
+	for _, tmpl := range results {
+		if tmpl == nil || tmpl != results[0] {
+			t.Errorf("templates() = %p, expected the built-in templates %p loaded once", tmpl, results[0])
+		}
+	}
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Default heading depths listed in the TOC.
+const (
+	DefaultTOCMinDepth = 2
+	DefaultTOCMaxDepth = 3
+)
+
+// ValidateTOCDepth checks the heading depths of the TOC, zero is the default.
+func ValidateTOCDepth(minDepth, maxDepth int) error {
+	if minDepth >= 0 && maxDepth >= 0 {
+		minDepth, maxDepth = (&GenerationOption{TOCMinDepth: minDepth, TOCMaxDepth: maxDepth}).tocDepth()
+		if maxDepth <= 6 && minDepth <= maxDepth {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid toc depth: %d-%d, depths must be between 1 and 6", minDepth, maxDepth)
+}
+
+func (genOpt *GenerationOption) tocDepth() (int, int) {
+	minDepth, maxDepth := genOpt.TOCMinDepth, genOpt.TOCMaxDepth
+	if minDepth <= 0 {
+		minDepth = DefaultTOCMinDepth
+	}
+	if maxDepth <= 0 {
+		maxDepth = DefaultTOCMaxDepth
+	}
+	return minDepth, maxDepth
+}
+
+type tocHeading struct {
+	level int
+	title string
+	slug  string
+	// start is the offset of the line of the heading.
+	start int
+}
+
+type tocDoc struct {
+	headings []*tocHeading
+	// titleEnd is the offset after the line of the first-level title, -1
+	// if there is none.
+	titleEnd int
+}
+
+// parseTOC finds the headings and the title of src.
+func parseTOC(md goldmark.Markdown, src []byte) *tocDoc {
+	doc := &tocDoc{titleEnd: -1}
+	slugs := make(map[string]int)
+	ast.Walk(md.Parser().Parse(text.NewReader(src)), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || n.Type() != ast.TypeBlock || n.Lines().Len() == 0 {
+			return ast.WalkContinue, nil
+		}
+		first, last := n.Lines().At(0), n.Lines().At(n.Lines().Len()-1)
+		switch n := n.(type) {
+		case *ast.Heading:
+			// numbered like GitHub if repeated
+			title := nodeText(n, src)
+			slug := githubSlug(title)
+			if i := slugs[slug]; i > 0 {
+				slugs[slug]++
+				slug = fmt.Sprintf("%s-%d", slug, i)
+			} else {
+				slugs[slug]++
+			}
+			doc.headings = append(doc.headings, &tocHeading{level: n.Level, title: title, slug: slug, start: lineStart(src, first.Start)})
+
+			if n.Level == 1 && doc.titleEnd < 0 {
+				end := lineEnd(src, last.Stop)
+				// the underline of a setext heading
+				if next := lineEnd(src, end); next > end && len(bytes.Trim(src[end:next], "= \t\r\n")) == 0 {
+					end = next
+				}
+				doc.titleEnd = end
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return doc
+}
+
+// renderTOC renders the TOC list, indented from the shallowest heading.
+func renderTOC(headings []*tocHeading) string {
+	top := 6
+	for _, h := range headings {
+		top = min(top, h.level)
+	}
+	var sb strings.Builder
+	for _, h := range headings {
+		title := strings.NewReplacer("[", `\[`, "]", `\]`).Replace(h.title)
+		fmt.Fprintf(&sb, "%s- [%s](#%s)\n", strings.Repeat("  ", h.level-top), title, h.slug)
+	}
+	return sb.String()
+}
+
+// updateTOC replaces the TOC between the markers of body, or inserts it
+// after the first-level title, or before the first listed heading if
+// there is no title. A body without markers nor listed headings is kept.
+// The headings of the backlinks section are not listed.
+func updateTOC(md goldmark.Markdown, body string, minDepth, maxDepth int) string {
+	doc := parseTOC(md, []byte(body))
+	r := findRegion(md, []byte(body), TOCStart, TOCEnd)
+	backlinks := findRegion(md, []byte(body), BacklinksStart, BacklinksEnd)
+	var headings []*tocHeading
+	for _, h := range doc.headings {
+		if backlinks.found() && h.start >= backlinks.start && h.start < backlinks.end {
+			continue
+		}
+		if h.level >= minDepth && h.level <= maxDepth {
+			headings = append(headings, h)
+		}
+	}
+
+	if r.found() {
+		return r.replace(body, renderTOC(headings))
+	}
+	if len(headings) == 0 {
+		return body
+	}
+
+	pos := headings[0].start
+	if doc.titleEnd >= 0 {
+		pos = doc.titleEnd
+	}
+	// the TOC is a block of its own
+	before, after := body[:pos], body[pos:]
+	for before != "" && !strings.HasSuffix(before, "\n\n") {
+		before += "\n"
+	}
+	if after != "" && !strings.HasPrefix(after, "\n") {
+		after = "\n" + after
+	}
+	return before + r.wrap(renderTOC(headings)) + after
+}
+
+// removeTOC removes the TOC and its markers from body.
+func removeTOC(md goldmark.Markdown, body string) string {
+	if r := findRegion(md, []byte(body), TOCStart, TOCEnd); r.found() {
+		return r.remove(body)
+	}
+	return body
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"testing"
+)
+
+func TestUpdateTOC(t *testing.T) {
+	toc := TOCStart + "\n- [A](#a)\n  - [A.1](#a1)\n- [A](#a-1)\n" + TOCEnd + "\n"
+	testdata := []struct {
+		body     string
+		expected string
+	}{
+		// inserted after the title
+		{"# Title\n\nIntro\n\n## A\n\n### A.1\n\n## A\n\n#### Deep\n",
+			"# Title\n\n" + toc + "\nIntro\n\n## A\n\n### A.1\n\n## A\n\n#### Deep\n"},
+		{"Title\n=====\nIntro\n\n## A\n\n### A.1\n\n## A\n",
+			"Title\n=====\n\n" + toc + "\nIntro\n\n## A\n\n### A.1\n\n## A\n"},
+		// inserted before the first heading without title
+		{"Intro\n\n## A\n\n### A.1\n\n## A\n",
+			"Intro\n\n" + toc + "\n## A\n\n### A.1\n\n## A\n"},
+		// updated between the markers
+		{"# Title\n\n" + TOCStart + "\n- [Old](#old)\n" + TOCEnd + "\n\n## A\n\n### A.1\n\n## A\n",
+			"# Title\n\n" + toc + "\n## A\n\n### A.1\n\n## A\n"},
+		{"# Title\n\n" + TOCStart + "\n- [Old](#old)\n" + TOCEnd + "\n",
+			"# Title\n\n" + TOCStart + "\n" + TOCEnd + "\n"},
+		// headings and markers in code
+		{"# Title\n\n```\n" + TOCStart + "\n## Code\n```\n",
+			"# Title\n\n```\n" + TOCStart + "\n## Code\n```\n"},
+		// headings of the backlinks section
+		{"# Title\n\n" + BacklinksStart + "\n## Linked from\n\n- [B](b.md)\n" + BacklinksEnd + "\n",
+			"# Title\n\n" + BacklinksStart + "\n## Linked from\n\n- [B](b.md)\n" + BacklinksEnd + "\n"},
+		{"# Title\n", "# Title\n"},
+		{"", ""},
+	}
+	for _, d := range testdata {
+		actual := updateTOC(newMarkdown(), d.body, 2, 3)
+		if actual != d.expected {
+			t.Errorf("updateTOC(%q) = %q, expected %q", d.body, actual, d.expected)
+		}
+		if d.body != "" && d.body != "# Title\n" && updateTOC(newMarkdown(), actual, 2, 3) != actual {
+			t.Errorf("updateTOC(%q) is not stable", actual)
+		}
+	}
+
+	if actual, expected := updateTOC(newMarkdown(), "# [x] `Title`\n\n## [x] `A`\n", 1, 1), "# [x] `Title`\n\n"+TOCStart+"\n- [\\[x\\] Title](#x-title)\n"+TOCEnd+"\n\n## [x] `A`\n"; actual != expected {
+		t.Errorf("updateTOC(depth 1) = %q, expected %q", actual, expected)
+	}
+}
+
+func TestRemoveTOC(t *testing.T) {
+	testdata := []struct {
+		body     string
+		expected string
+	}{
+		{"# Title\n\nIntro\n\n## A\n", "# Title\n\nIntro\n\n## A\n"},
+		{"Intro\n\n## A\n", "Intro\n\n## A\n"},
+		{"# Title\n\n" + TOCStart + "\n" + TOCEnd + "\n", "# Title\n"},
+		{"```\n" + TOCStart + "\n" + TOCEnd + "\n```\n", "```\n" + TOCStart + "\n" + TOCEnd + "\n```\n"},
+	}
+	for _, d := range testdata {
+		body := updateTOC(newMarkdown(), d.body, 2, 3)
+		actual := removeTOC(newMarkdown(), body)
+		if actual != d.expected {
+			t.Errorf("removeTOC(%q) = %q, expected %q", body, actual, d.expected)
+		}
+	}
+}
+
+func TestValidateTOCDepth(t *testing.T) {
+	testdata := []struct {
+		minDepth, maxDepth int
+		valid              bool
+	}{
+		{0, 0, true},
+		{1, 6, true},
+		{4, 4, true},
+		{4, 0, false},
+		{3, 2, false},
+		{2, 7, false},
+		{-1, 3, false},
+	}
+	for _, d := range testdata {
+		if err := ValidateTOCDepth(d.minDepth, d.maxDepth); (err == nil) != d.valid {
+			t.Errorf("ValidateTOCDepth(%d, %d) = %v, expected valid %v", d.minDepth, d.maxDepth, err, d.valid)
+		}
+	}
+}
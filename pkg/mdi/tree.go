@@ -0,0 +1,154 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+)
+
+// Title returns the title of the index.
+func (idx *Index) Title() string {
+	return idx.title
+}
+
+// NavTitle returns the title of the index in breadcrumbs, `nav_title` of
+// the index file front matter if set.
+func (idx *Index) NavTitle() string {
+	return idx.navTitle()
+}
+
+// HomeTitle returns the title of the root index in breadcrumbs.
+func (idx *Index) HomeTitle() string {
+	return idx.homeTitle
+}
+
+// Dir returns the directory of the index.
+func (idx *Index) Dir() string {
+	return idx.workDir
+}
+
+// File returns the index file, it may not exist yet.
+func (idx *Index) File() string {
+	return idx.file
+}
+
+// RelPath returns the index file relative to the work dir of the root index.
+func (idx *Index) RelPath() string {
+	return idx.Root().relPath(idx.file)
+}
+
+// Root returns the root index of the tree.
+func (idx *Index) Root() *Index {
+	return idx.chains[0]
+}
+
+// Parent returns the parent index, nil for the root index.
+func (idx *Index) Parent() *Index {
+	if len(idx.chains) < 2 {
+		return nil
+	}
+	return idx.chains[len(idx.chains)-2]
+}
+
+// Breadcrumbs returns the indexes from the root index down to idx, included.
+func (idx *Index) Breadcrumbs() []*Index {
+	return slices.Clone(idx.chains)
+}
+
+// Children returns the sub indexes, in sort order.
+func (idx *Index) Children() []*Index {
+	return slices.Clone(idx.children)
+}
+
+// Entries returns the markdown files of the index, in sort order.
+func (idx *Index) Entries() []*Entry {
+	return slices.Clone(idx.entries)
+}
+
+// Skipped returns the paths of the index dir left out of the index, in
+// dir order. Ignored paths are recorded for directories and markdown files only.
+func (idx *Index) Skipped() []*Skip {
+	return slices.Clone(idx.skipped)
+}
+
+// Walk calls fn for idx and every index below it, parents before children.
+// If fn returns fs.SkipDir the children of that index are skipped, any
+// other error stops the walk and is returned.
+func (idx *Index) Walk(fn func(idx *Index) error) error {
+	if err := fn(idx); err != nil {
+		if err == fs.SkipDir {
+			return nil
+		}
+		return err
+	}
+	for _, child := range idx.children {
+		if err := child.Walk(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (idx *Index) relPath(file string) string {
+	rel, err := filepath.Rel(idx.workDir, file)
+	if err != nil {
+		return file
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Title returns the title of the entry, from front matter or its first-level title.
+func (e *Entry) Title() string {
+	return e.title
+}
+
+// NavTitle returns the title of the entry in nav, `nav_title` of its front matter if set.
+func (e *Entry) NavTitle() string {
+	return e.navTitle()
+}
+
+// File returns the markdown file of the entry.
+func (e *Entry) File() string {
+	return e.file
+}
+
+// RelPath returns the file relative to the work dir of the root index.
+func (e *Entry) RelPath() string {
+	return e.index.Root().relPath(e.file)
+}
+
+// Index returns the index listing the entry.
+func (e *Entry) Index() *Index {
+	return e.index
+}
+
+// Breadcrumbs returns the indexes from the root index down to the index listing the entry.
+func (e *Entry) Breadcrumbs() []*Index {
+	return e.index.Breadcrumbs()
+}
+
+// Prev returns the previous entry of the same index, nil for the first one.
+func (e *Entry) Prev() *Entry {
+	return e.prev
+}
+
+// Next returns the next entry of the same index, nil for the last one.
+func (e *Entry) Next() *Entry {
+	return e.next
+}
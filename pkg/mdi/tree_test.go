@@ -0,0 +1,86 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"io/fs"
+	"path"
+	"slices"
+	"testing"
+)
+
+func TestIndexTree(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"root.md":                "# Root\n",
+		"go/hello.md":            "# Hello\n",
+		"go/vars.md":             "---\nnav_title: Vars\n---\n# Variables\n",
+		"go/basics/intro.md":     "# Intro\n",
+		"go/zz_generated_mdi.md": "# Golang\n",
+		"rust/intro.md":          "# Rust\n",
+	})
+	idx, err := NewIndex(&IndexOption{WorkDir: dir, IndexTitle: "Notes", RootIndexFile: path.Join(dir, "README.md"), SubIndexFile: defaultIndexFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if idx.Title() != "Notes" || idx.RelPath() != "README.md" || idx.Parent() != nil || idx.Root() != idx {
+		t.Errorf("root index = %q, %q, unexpected", idx.Title(), idx.RelPath())
+	}
+
+	var titles []string
+	idx.Walk(func(i *Index) error {
+		titles = append(titles, i.Title())
+		return nil
+	})
+	if expected := []string{"Notes", "Golang", "basics", "rust"}; !slices.Equal(titles, expected) {
+		t.Errorf("Walk() visited %v, expected %v", titles, expected)
+	}
+
+	titles = nil
+	idx.Walk(func(i *Index) error {
+		titles = append(titles, i.Title())
+		if i.Title() == "Golang" {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if expected := []string{"Notes", "Golang", "rust"}; !slices.Equal(titles, expected) {
+		t.Errorf("Walk() with SkipDir visited %v, expected %v", titles, expected)
+	}
+
+	goIdx := idx.Children()[0]
+	basics := goIdx.Children()[0]
+	if basics.RelPath() != "go/basics/"+defaultIndexFile || basics.Parent() != goIdx {
+		t.Errorf("basics index = %q, unexpected", basics.RelPath())
+	}
+	if crumbs := basics.Breadcrumbs(); len(crumbs) != 3 || crumbs[0] != idx || crumbs[2] != basics {
+		t.Errorf("Breadcrumbs() = %v, expected root, go and basics", crumbs)
+	}
+
+	entries := goIdx.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Entries() = %d entries, expected 2", len(entries))
+	}
+	hello, vars := entries[0], entries[1]
+	if hello.RelPath() != "go/hello.md" || hello.Index() != goIdx || hello.Prev() != nil || hello.Next() != vars {
+		t.Errorf("hello entry = %q, unexpected", hello.RelPath())
+	}
+	if vars.Title() != "Variables" || vars.NavTitle() != "Vars" || vars.Prev() != hello || vars.Next() != nil {
+		t.Errorf("vars entry = %q, %q, unexpected", vars.Title(), vars.NavTitle())
+	}
+}
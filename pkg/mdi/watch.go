@@ -0,0 +1,262 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/poneding/mdi/pkg/util"
+)
+
+// DefaultDebounce is the quiet period waited for after a change before regenerating.
+const DefaultDebounce = 300 * time.Millisecond
+
+// ignoreFiles change which files are indexed, a change to any of them regenerates the whole tree.
+var ignoreFiles = []string{ConfigFile, ".mdiignore", ".gitignore"}
+
+type watcher struct {
+	fsw      *fsnotify.Watcher
+	idxOpt   IndexOption
+	genOpt   *GenerationOption
+	debounce time.Duration
+	dirs     map[string]bool
+	// written holds the content of the files written by the watcher, to
+	// tell its own writes from the ones of the user.
+	written map[string][]byte
+	// cache is kept between the runs, the changed files are invalidated.
+	cache *cache
+}
+
+// Watch generates the index, then watches the work dir and regenerates the
+// files affected by each burst of changes until ctx is done.
+func Watch(ctx context.Context, idxOpt *IndexOption, genOpt *GenerationOption, debounce time.Duration) error {
+	w, err := newWatcher(idxOpt, genOpt, debounce)
+	if err != nil {
+		return err
+	}
+	defer w.fsw.Close()
+	return w.run(ctx, w.generate)
+}
+
+func newWatcher(idxOpt *IndexOption, genOpt *GenerationOption, debounce time.Duration) (*watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &watcher{
+		fsw:      fsw,
+		idxOpt:   *idxOpt,
+		genOpt:   genOpt,
+		debounce: util.If(debounce > 0, debounce, DefaultDebounce),
+		dirs:     make(map[string]bool),
+		written:  make(map[string][]byte),
+		cache:    newCache(idxOpt.filesystem(), 0),
+	}
+	if w.idxOpt.WorkDir == "" {
+		w.idxOpt.WorkDir = defaultIndexOption.WorkDir
+	}
+	if err := w.addDir(w.idxOpt.WorkDir); err != nil {
+		fsw.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// run calls fn with nil, then with the changed paths of each burst of
+// changes until ctx is done.
+func (w *watcher) run(ctx context.Context, fn func(changed []string) error) error {
+	if err := fn(nil); err != nil {
+		fmt.Printf("ERROR: %s\n", err)
+	}
+
+	var pending []string
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Printf("ERROR: failed to watch: %s\n", err)
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return nil
+			}
+			// same form as the paths of NewIndex
+			ev.Name = path.Clean(filepath.ToSlash(ev.Name))
+			if !w.relevant(ev) {
+				continue
+			}
+			if ev.Has(fsnotify.Create) {
+				if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+					w.addDir(ev.Name)
+				}
+			}
+			if !slices.Contains(pending, ev.Name) {
+				pending = append(pending, ev.Name)
+			}
+			timer.Reset(w.debounce)
+		case <-timer.C:
+			if w.genOpt.Verbose {
+				fmt.Printf("CHANGED: %s\n", strings.Join(pending, ", "))
+			}
+			if err := fn(pending); err != nil {
+				fmt.Printf("ERROR: %s\n", err)
+			}
+			pending = nil
+		}
+	}
+}
+
+// addDir watches dir and its subdirectories, except the excluded ones.
+func (w *watcher) addDir(dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if p != w.idxOpt.WorkDir && (d.Name() == ".git" || matchRule(w.idxOpt.rootRules(), strings.Split(p, "/"), true) != nil) {
+			return filepath.SkipDir
+		}
+		if err := w.fsw.Add(p); err != nil {
+			return err
+		}
+		w.dirs[path.Clean(filepath.ToSlash(p))] = true
+		return nil
+	})
+}
+
+// relevant reports whether ev may change the generated files.
+func (w *watcher) relevant(ev fsnotify.Event) bool {
+	if ev.Has(fsnotify.Chmod) && !ev.Has(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) {
+		return false
+	}
+	if b, ok := w.written[ev.Name]; ok {
+		if cur, err := os.ReadFile(ev.Name); err == nil && bytes.Equal(cur, b) {
+			return false
+		}
+	}
+
+	name := path.Base(ev.Name)
+	if slices.Contains(mdExts, path.Ext(name)) || slices.Contains(ignoreFiles, name) {
+		return true
+	}
+	if w.dirs[ev.Name] {
+		if ev.Has(fsnotify.Remove | fsnotify.Rename) {
+			delete(w.dirs, ev.Name)
+		}
+		return true
+	}
+	fi, err := os.Stat(ev.Name)
+	return err == nil && fi.IsDir()
+}
+
+// generate regenerates the files affected by the changed paths, all files if changed is nil.
+func (w *watcher) generate(changed []string) error {
+	idx, full, err := w.index(changed)
+	if idx == nil {
+		return err
+	}
+
+	p := &Plan{fsys: idx.fsys}
+	if !full {
+		p.filter = w.affected(idx, changed)
+	}
+	err = errors.Join(err, idx.plan(p, w.genOpt), p.Apply(w.genOpt))
+
+	for _, c := range p.Changes {
+		if c.Kind == Deleted {
+			delete(w.written, c.File)
+		} else {
+			w.written[c.File] = c.After
+		}
+	}
+	return err
+}
+
+// index rebuilds the index tree after the changed paths, full is true if
+// every file may be affected.
+func (w *watcher) index(changed []string) (*Index, bool, error) {
+	full := changed == nil
+	for _, file := range changed {
+		if slices.Contains(ignoreFiles, path.Base(file)) {
+			full = true
+		}
+	}
+	if full {
+		w.cache.reset()
+	} else {
+		for _, file := range changed {
+			w.cache.invalidate(file)
+		}
+	}
+
+	// a fresh copy, so that ignore files are read again
+	idxOpt := w.idxOpt
+	idxOpt.cache = w.cache
+	idx, err := NewIndex(&idxOpt)
+	return idx, full, err
+}
+
+// affected returns a filter accepting the files whose content may depend
+// on the changed paths: the index files of their directory and its
+// parents, and their neighbor entries. A changed directory or index file
+// also affects the nav of its whole subtree.
+func (w *watcher) affected(idx *Index, changed []string) func(string) bool {
+	indexes := make(map[string]bool)
+	idx.Walk(func(idx *Index) error {
+		indexes[idx.file] = true
+		return nil
+	})
+
+	var dirs, trees []string
+	for _, file := range changed {
+		dir := path.Dir(file)
+		dirs = append(dirs, dir)
+		if indexes[file] || w.dirs[file] || !slices.Contains(mdExts, path.Ext(file)) {
+			trees = append(trees, util.If(w.dirs[file], file, dir))
+		}
+	}
+
+	return func(file string) bool {
+		for _, dir := range dirs {
+			if path.Dir(file) == dir || (indexes[file] && isWithin(dir, path.Dir(file))) {
+				return true
+			}
+		}
+		for _, dir := range trees {
+			if isWithin(file, dir) {
+				return true
+			}
+		}
+		return false
+	}
+}
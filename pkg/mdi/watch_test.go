@@ -0,0 +1,100 @@
+/*
+Copyright 2023 Pone Ding <poneding@gmail.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mdi
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func writeTree(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := path.Join(dir, name)
+		if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWatcherAffected(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"root.md":                "# Root\n",
+		"go/hello.md":            "# Hello\n",
+		"go/vars.md":             "# Vars\n",
+		"go/basics/intro.md":     "# Intro\n",
+		"rust/intro.md":          "# Rust\n",
+		"go/zz_generated_mdi.md": "# Go\n",
+	})
+	idx, err := NewIndex(&IndexOption{WorkDir: dir, RootIndexFile: path.Join(dir, "README.md"), SubIndexFile: defaultIndexFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &watcher{dirs: map[string]bool{dir: true, path.Join(dir, "go"): true, path.Join(dir, "go/basics"): true, path.Join(dir, "rust"): true}}
+
+	testdata := []struct {
+		changed  string
+		file     string
+		expected bool
+	}{
+		{"go/hello.md", "go/vars.md", true},
+		{"go/hello.md", "go/zz_generated_mdi.md", true},
+		{"go/hello.md", "README.md", true},
+		{"go/hello.md", "go/basics/intro.md", false},
+		{"go/hello.md", "go/basics/zz_generated_mdi.md", false},
+		{"go/hello.md", "rust/intro.md", false},
+		{"go/zz_generated_mdi.md", "go/basics/intro.md", true},
+		{"go/zz_generated_mdi.md", "rust/intro.md", false},
+		{"go/basics", "go/basics/intro.md", true},
+		{"go/basics", "go/hello.md", true},
+		{"go/basics", "rust/zz_generated_mdi.md", false},
+	}
+	for _, d := range testdata {
+		affected := w.affected(idx, []string{path.Join(dir, d.changed)})
+		if actual := affected(path.Join(dir, d.file)); actual != d.expected {
+			t.Errorf("affected(%q)(%q) = %v, expected %v", d.changed, d.file, actual, d.expected)
+		}
+	}
+}
+
+func TestInvalidateCache(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"go/hello.md": "# Hello\n"})
+	file := path.Join(dir, "go/hello.md")
+
+	c := newCache(OSFS{}, 0)
+	if title := c.readTitle(file); title != "Hello" {
+		t.Fatalf("readTitle() = %q, expected %q", title, "Hello")
+	}
+	if ok, _ := c.hasMdFile(path.Join(dir, "go"), defaultIndexFile); !ok {
+		t.Fatalf("hasMdFile() = false, expected true")
+	}
+
+	writeTree(t, dir, map[string]string{"go/hello.md": "---\ntitle: Hi\ndraft: true\n---\n"})
+	c.invalidate(file)
+	if title := c.readTitle(file); title != "Hi" {
+		t.Errorf("readTitle() = %q after invalidateCache, expected %q", title, "Hi")
+	}
+	if ok, _ := c.hasMdFile(path.Join(dir, "go"), defaultIndexFile); ok {
+		t.Errorf("hasMdFile() = true after invalidateCache, expected false")
+	}
+}